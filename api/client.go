@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/calvinmclean/goblin/auth"
+	"github.com/calvinmclean/goblin/dns"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Client uses the gRPC API to get IPs and manage fallback routes. It implements plugins.ReadyGetter so it can
+// also be used to run plugins with readiness probes, and containers.Registrar so it can register containers
+type Client struct {
+	conn   *grpc.ClientConn
+	client GoblinClient
+	token  string
+}
+
+// NewClient creates a Client for the gRPC server at addr, which is a host and port or the path to a Unix socket
+// like unix:///path/to/goblin.sock. If the API token file exists, its token is used to authenticate requests
+func NewClient(addr string) (*Client, error) {
+	token, err := auth.ClientToken()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating gRPC client: %w", err)
+	}
+
+	return &Client{conn, NewGoblinClient(conn), token}, nil
+}
+
+// Close closes the connection to the server
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// context adds the token to the outgoing metadata
+func (c *Client) context(ctx context.Context) context.Context {
+	if c.token == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", auth.Authorization(c.token))
+}
+
+// GetIP allocates an IP for the subdomain. The IP stays allocated until the context is done
+func (c *Client) GetIP(ctx context.Context, subdomain string) (string, error) {
+	return c.allocate(ctx, subdomain, false)
+}
+
+// GetPendingIP allocates an IP like GetIP, but DNS keeps using the subdomain's fallback route until SetReady
+// is called
+func (c *Client) GetPendingIP(ctx context.Context, subdomain string) (string, error) {
+	return c.allocate(ctx, subdomain, true)
+}
+
+// SetReady tells the server that the subdomain's pending IP is ready to be used by DNS
+func (c *Client) SetReady(ctx context.Context, subdomain string) error {
+	_, err := c.client.SetReady(c.context(ctx), &SetReadyRequest{Subdomain: subdomain})
+	return fromStatus(err)
+}
+
+// SetPending tells the server to use the subdomain's fallback route again until SetReady is called, while the IP
+// stays allocated
+func (c *Client) SetPending(ctx context.Context, subdomain string) error {
+	_, err := c.client.SetPending(c.context(ctx), &SetPendingRequest{Subdomain: subdomain})
+	return fromStatus(err)
+}
+
+func (c *Client) allocate(ctx context.Context, subdomain string, pending bool) (string, error) {
+	stream, err := c.client.Allocate(c.context(ctx), &AllocateRequest{Subdomain: subdomain, Pending: pending})
+	if err != nil {
+		return "", fmt.Errorf("failed to send request to server: %w", err)
+	}
+
+	lease, err := stream.Recv()
+	if err != nil {
+		return "", fmt.Errorf("failed to get lease: %w", fromStatus(err))
+	}
+
+	// the lease ends when the context is done and the stream is canceled, so keep receiving until then
+	go func() {
+		for {
+			_, err := stream.Recv()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return lease.GetIp(), nil
+}
+
+// RegisterFallback registers a fallback route with the server. If expiresIn is not zero, the server
+// removes the route after that duration
+func (c *Client) RegisterFallback(ctx context.Context, subdomain, address string, expiresIn time.Duration) error {
+	req := &RegisterFallbackRequest{Subdomain: subdomain, Address: address}
+	if expiresIn > 0 {
+		req.Expire = durationpb.New(expiresIn)
+	}
+
+	_, err := c.client.RegisterFallback(c.context(ctx), req)
+	return fromStatus(err)
+}
+
+// List gets all IP allocations and fallback routes from the server
+func (c *Client) List(ctx context.Context) ([]dns.Record, []dns.Fallback, error) {
+	resp, err := c.client.List(c.context(ctx), &ListRequest{})
+	if err != nil {
+		return nil, nil, fromStatus(err)
+	}
+
+	records := make([]dns.Record, 0, len(resp.GetRecords()))
+	for _, r := range resp.GetRecords() {
+		records = append(records, dns.Record{
+			Subdomain:   r.GetSubdomain(),
+			IP:          r.GetIp(),
+			Active:      r.GetActive(),
+			Pending:     r.GetPending(),
+			AllocatedAt: r.GetAllocatedAt().AsTime(),
+			RemovedAt:   optionalTime(r.GetRemovedAt()),
+		})
+	}
+
+	fallbacks := make([]dns.Fallback, 0, len(resp.GetFallbacks()))
+	for _, f := range resp.GetFallbacks() {
+		fallbacks = append(fallbacks, dns.Fallback{
+			Subdomain: f.GetSubdomain(),
+			Route: dns.Route{
+				Target: f.GetTarget(),
+				TTL:    dns.Duration(f.GetTtl().AsDuration()),
+			},
+			Source:    f.GetSource(),
+			ExpiresAt: optionalTime(f.GetExpiresAt()),
+		})
+	}
+
+	return records, fallbacks, nil
+}
+
+// RemoveFallback removes the fallback route for a subdomain from the server
+func (c *Client) RemoveFallback(ctx context.Context, subdomain string) error {
+	_, err := c.client.Remove(c.context(ctx), &RemoveRequest{Subdomain: subdomain})
+	return fromStatus(err)
+}
+
+// Watch streams events from the server. The channel is closed when the context is done or the
+// connection to the server is lost
+func (c *Client) Watch(ctx context.Context) (<-chan dns.Event, error) {
+	stream, err := c.client.Watch(c.context(ctx), &WatchRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to server: %w", err)
+	}
+
+	events := make(chan dns.Event)
+	go func() {
+		defer close(events)
+
+		for {
+			e, err := stream.Recv()
+			if err != nil {
+				return
+			}
+
+			select {
+			case events <- dns.Event{
+				Type:      dns.EventType(e.GetType()),
+				Time:      e.GetTime().AsTime(),
+				Subdomain: e.GetSubdomain(),
+				IP:        e.GetIp(),
+				Target:    e.GetTarget(),
+				Error:     e.GetError(),
+			}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// statusError keeps the message from the server while allowing the dns package's errors to be checked with errors.Is
+type statusError struct {
+	err error
+	msg string
+}
+
+func (e statusError) Error() string { return e.msg }
+func (e statusError) Unwrap() error { return e.err }
+
+// fromStatus converts the gRPC status code back to the dns package's errors
+func fromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		return statusError{dns.ErrNotFound, st.Message()}
+	case codes.PermissionDenied:
+		return statusError{dns.ErrReservedSubdomain, st.Message()}
+	case codes.FailedPrecondition:
+		return statusError{dns.ErrConfiguredRoute, st.Message()}
+	case codes.AlreadyExists:
+		return statusError{dns.ErrSubdomainInUse, st.Message()}
+	case codes.ResourceExhausted:
+		return statusError{dns.ErrNoAvailableIPs, st.Message()}
+	}
+	return err
+}
+
+func optionalTime(t *timestamppb.Timestamp) *time.Time {
+	if t == nil {
+		return nil
+	}
+	result := t.AsTime()
+	return &result
+}
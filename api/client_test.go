@@ -0,0 +1,254 @@
+package api
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calvinmclean/goblin/auth"
+	"github.com/calvinmclean/goblin/containers"
+	"github.com/calvinmclean/goblin/dns"
+	"github.com/calvinmclean/goblin/errors"
+	"github.com/calvinmclean/goblin/plugins"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// the Client can be used anywhere the HTTP client is used
+var (
+	_ plugins.ReadyGetter  = (*Client)(nil)
+	_ containers.Registrar = (*Client)(nil)
+)
+
+// fakeServer keeps the requests it receives and requires the token
+type fakeServer struct {
+	UnimplementedGoblinServer
+
+	token string
+
+	mu        sync.Mutex
+	pending   map[string]bool
+	fallbacks map[string]string
+}
+
+func (f *fakeServer) checkToken(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, header := range md.Get("authorization") {
+		if auth.ValidAuthorization(header, f.token) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid API token")
+}
+
+func (f *fakeServer) Allocate(req *AllocateRequest, stream grpc.ServerStreamingServer[Lease]) error {
+	err := f.checkToken(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.pending[req.GetSubdomain()] = req.GetPending()
+	f.mu.Unlock()
+
+	err = stream.Send(&Lease{Subdomain: req.GetSubdomain(), Ip: "10.0.0.4"})
+	if err != nil {
+		return err
+	}
+
+	<-stream.Context().Done()
+	return nil
+}
+
+func (f *fakeServer) SetReady(ctx context.Context, req *SetReadyRequest) (*SetReadyResponse, error) {
+	err := f.checkToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.pending[req.GetSubdomain()]; !ok {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	f.pending[req.GetSubdomain()] = false
+
+	return &SetReadyResponse{}, nil
+}
+
+func (f *fakeServer) RegisterFallback(ctx context.Context, req *RegisterFallbackRequest) (*RegisterFallbackResponse, error) {
+	err := f.checkToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fallbacks[req.GetSubdomain()] = req.GetAddress()
+
+	return &RegisterFallbackResponse{}, nil
+}
+
+func (f *fakeServer) Remove(ctx context.Context, req *RemoveRequest) (*RemoveResponse, error) {
+	err := f.checkToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GetSubdomain() {
+	case "goblin":
+		return nil, status.Error(codes.PermissionDenied, "subdomain is reserved")
+	case "config":
+		return nil, status.Error(codes.FailedPrecondition, "route is set in the fallback config")
+	}
+
+	return &RemoveResponse{}, nil
+}
+
+func (f *fakeServer) isPending(subdomain string) (pending, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pending, ok = f.pending[subdomain]
+	return pending, ok
+}
+
+// startFakeServer serves the fakeServer on a Unix socket and returns the address for NewClient
+func startFakeServer(t *testing.T, token string) (*fakeServer, string) {
+	t.Helper()
+
+	// Unix socket paths have a short length limit, so t.TempDir might be too long
+	dir, err := os.MkdirTemp("", "goblin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "grpc.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeServer{token: token, pending: map[string]bool{}, fallbacks: map[string]string{}}
+	server := grpc.NewServer()
+	RegisterGoblinServer(server, fake)
+	go func() {
+		_ = server.Serve(ln)
+	}()
+	t.Cleanup(server.Stop)
+
+	return fake, "unix://" + socket
+}
+
+// newTestClient creates a Client that reads the token from a file like the CLI does
+func newTestClient(t *testing.T, addr, token string) *Client {
+	t.Helper()
+
+	fname := filepath.Join(t.TempDir(), "token")
+	if token != "" {
+		err := os.WriteFile(fname, []byte(token), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv(auth.TokenFileEnvVar, fname)
+
+	client, err := NewClient(addr)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestClient(t *testing.T) {
+	fake, addr := startFakeServer(t, "abc123")
+	client := newTestClient(t, addr, "abc123")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("GetPendingIP", func(t *testing.T) {
+		ip, err := client.GetPendingIP(ctx, "app")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ip != "10.0.0.4" {
+			t.Errorf("expected 10.0.0.4, got %s", ip)
+		}
+
+		pending, _ := fake.isPending("app")
+		if !pending {
+			t.Error("expected pending allocation")
+		}
+	})
+
+	t.Run("SetReady", func(t *testing.T) {
+		err := client.SetReady(ctx, "app")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		pending, _ := fake.isPending("app")
+		if pending {
+			t.Error("expected ready allocation")
+		}
+	})
+
+	t.Run("SetReadyNotFound", func(t *testing.T) {
+		err := client.SetReady(ctx, "missing")
+		if !errors.Is(err, dns.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("RegisterFallback", func(t *testing.T) {
+		err := client.RegisterFallback(ctx, "db", "192.168.1.10", 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		if fake.fallbacks["db"] != "192.168.1.10" {
+			t.Errorf("expected fallback to be registered, got %v", fake.fallbacks)
+		}
+	})
+
+	t.Run("RemoveReserved", func(t *testing.T) {
+		err := client.RemoveFallback(ctx, "goblin")
+		if !errors.Is(err, dns.ErrReservedSubdomain) {
+			t.Errorf("expected ErrReservedSubdomain, got %v", err)
+		}
+	})
+
+	t.Run("RemoveConfigured", func(t *testing.T) {
+		err := client.RemoveFallback(ctx, "config")
+		if !errors.Is(err, dns.ErrConfiguredRoute) {
+			t.Errorf("expected ErrConfiguredRoute, got %v", err)
+		}
+	})
+}
+
+func TestClientWithoutToken(t *testing.T) {
+	_, addr := startFakeServer(t, "abc123")
+	client := newTestClient(t, addr, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.GetIP(ctx, "app")
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated, got %v", err)
+	}
+}
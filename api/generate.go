@@ -0,0 +1,4 @@
+// Package api has the gRPC service for the Goblin server and a client for it
+package api
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative goblin.proto
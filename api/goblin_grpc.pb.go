@@ -0,0 +1,356 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: goblin.proto
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Goblin_Allocate_FullMethodName         = "/goblin.v1.Goblin/Allocate"
+	Goblin_SetReady_FullMethodName         = "/goblin.v1.Goblin/SetReady"
+	Goblin_SetPending_FullMethodName       = "/goblin.v1.Goblin/SetPending"
+	Goblin_RegisterFallback_FullMethodName = "/goblin.v1.Goblin/RegisterFallback"
+	Goblin_List_FullMethodName             = "/goblin.v1.Goblin/List"
+	Goblin_Remove_FullMethodName           = "/goblin.v1.Goblin/Remove"
+	Goblin_Watch_FullMethodName            = "/goblin.v1.Goblin/Watch"
+)
+
+// GoblinClient is the client API for Goblin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GoblinClient interface {
+	Allocate(ctx context.Context, in *AllocateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Lease], error)
+	SetReady(ctx context.Context, in *SetReadyRequest, opts ...grpc.CallOption) (*SetReadyResponse, error)
+	SetPending(ctx context.Context, in *SetPendingRequest, opts ...grpc.CallOption) (*SetPendingResponse, error)
+	RegisterFallback(ctx context.Context, in *RegisterFallbackRequest, opts ...grpc.CallOption) (*RegisterFallbackResponse, error)
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	Remove(ctx context.Context, in *RemoveRequest, opts ...grpc.CallOption) (*RemoveResponse, error)
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type goblinClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGoblinClient(cc grpc.ClientConnInterface) GoblinClient {
+	return &goblinClient{cc}
+}
+
+func (c *goblinClient) Allocate(ctx context.Context, in *AllocateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Lease], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Goblin_ServiceDesc.Streams[0], Goblin_Allocate_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[AllocateRequest, Lease]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Goblin_AllocateClient = grpc.ServerStreamingClient[Lease]
+
+func (c *goblinClient) SetReady(ctx context.Context, in *SetReadyRequest, opts ...grpc.CallOption) (*SetReadyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetReadyResponse)
+	err := c.cc.Invoke(ctx, Goblin_SetReady_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goblinClient) SetPending(ctx context.Context, in *SetPendingRequest, opts ...grpc.CallOption) (*SetPendingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetPendingResponse)
+	err := c.cc.Invoke(ctx, Goblin_SetPending_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goblinClient) RegisterFallback(ctx context.Context, in *RegisterFallbackRequest, opts ...grpc.CallOption) (*RegisterFallbackResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterFallbackResponse)
+	err := c.cc.Invoke(ctx, Goblin_RegisterFallback_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goblinClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, Goblin_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goblinClient) Remove(ctx context.Context, in *RemoveRequest, opts ...grpc.CallOption) (*RemoveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveResponse)
+	err := c.cc.Invoke(ctx, Goblin_Remove_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goblinClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Goblin_ServiceDesc.Streams[1], Goblin_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Goblin_WatchClient = grpc.ServerStreamingClient[Event]
+
+// GoblinServer is the server API for Goblin service.
+// All implementations must embed UnimplementedGoblinServer
+// for forward compatibility.
+type GoblinServer interface {
+	Allocate(*AllocateRequest, grpc.ServerStreamingServer[Lease]) error
+	SetReady(context.Context, *SetReadyRequest) (*SetReadyResponse, error)
+	SetPending(context.Context, *SetPendingRequest) (*SetPendingResponse, error)
+	RegisterFallback(context.Context, *RegisterFallbackRequest) (*RegisterFallbackResponse, error)
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	Remove(context.Context, *RemoveRequest) (*RemoveResponse, error)
+	Watch(*WatchRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedGoblinServer()
+}
+
+// UnimplementedGoblinServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGoblinServer struct{}
+
+func (UnimplementedGoblinServer) Allocate(*AllocateRequest, grpc.ServerStreamingServer[Lease]) error {
+	return status.Errorf(codes.Unimplemented, "method Allocate not implemented")
+}
+func (UnimplementedGoblinServer) SetReady(context.Context, *SetReadyRequest) (*SetReadyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetReady not implemented")
+}
+func (UnimplementedGoblinServer) SetPending(context.Context, *SetPendingRequest) (*SetPendingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPending not implemented")
+}
+func (UnimplementedGoblinServer) RegisterFallback(context.Context, *RegisterFallbackRequest) (*RegisterFallbackResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterFallback not implemented")
+}
+func (UnimplementedGoblinServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedGoblinServer) Remove(context.Context, *RemoveRequest) (*RemoveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Remove not implemented")
+}
+func (UnimplementedGoblinServer) Watch(*WatchRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedGoblinServer) mustEmbedUnimplementedGoblinServer() {}
+func (UnimplementedGoblinServer) testEmbeddedByValue()                {}
+
+// UnsafeGoblinServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GoblinServer will
+// result in compilation errors.
+type UnsafeGoblinServer interface {
+	mustEmbedUnimplementedGoblinServer()
+}
+
+func RegisterGoblinServer(s grpc.ServiceRegistrar, srv GoblinServer) {
+	// If the following call pancis, it indicates UnimplementedGoblinServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Goblin_ServiceDesc, srv)
+}
+
+func _Goblin_Allocate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AllocateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GoblinServer).Allocate(m, &grpc.GenericServerStream[AllocateRequest, Lease]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Goblin_AllocateServer = grpc.ServerStreamingServer[Lease]
+
+func _Goblin_SetReady_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetReadyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoblinServer).SetReady(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Goblin_SetReady_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoblinServer).SetReady(ctx, req.(*SetReadyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Goblin_SetPending_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPendingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoblinServer).SetPending(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Goblin_SetPending_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoblinServer).SetPending(ctx, req.(*SetPendingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Goblin_RegisterFallback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterFallbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoblinServer).RegisterFallback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Goblin_RegisterFallback_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoblinServer).RegisterFallback(ctx, req.(*RegisterFallbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Goblin_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoblinServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Goblin_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoblinServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Goblin_Remove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoblinServer).Remove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Goblin_Remove_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoblinServer).Remove(ctx, req.(*RemoveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Goblin_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GoblinServer).Watch(m, &grpc.GenericServerStream[WatchRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Goblin_WatchServer = grpc.ServerStreamingServer[Event]
+
+// Goblin_ServiceDesc is the grpc.ServiceDesc for Goblin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Goblin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "goblin.v1.Goblin",
+	HandlerType: (*GoblinServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SetReady",
+			Handler:    _Goblin_SetReady_Handler,
+		},
+		{
+			MethodName: "SetPending",
+			Handler:    _Goblin_SetPending_Handler,
+		},
+		{
+			MethodName: "RegisterFallback",
+			Handler:    _Goblin_RegisterFallback_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _Goblin_List_Handler,
+		},
+		{
+			MethodName: "Remove",
+			Handler:    _Goblin_Remove_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Allocate",
+			Handler:       _Goblin_Allocate_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _Goblin_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "goblin.proto",
+}
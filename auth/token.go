@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+const (
+	// TokenFileEnvVar can be used to change the location of the token file for the server and clients
+	TokenFileEnvVar = "GOBLIN_TOKEN_FILE"
+	// TokenQueryParam can be used to authenticate when the Authorization header can't be set, like in a browser
+	TokenQueryParam = "token"
+
+	tokenBytes = 32
+)
+
+const insecureTokenFileInstruction = `The token file must only be readable by your user. Fix the permissions with:
+
+  chmod 600 %s
+`
+
+// TokenFile is the path to the file that stores the API token. It is read from the GOBLIN_TOKEN_FILE
+// environment variable, or defaults to goblin/token in the user's config directory
+func TokenFile() (string, error) {
+	if fname := os.Getenv(TokenFileEnvVar); fname != "" {
+		return fname, nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting config directory: %w", err)
+	}
+
+	return filepath.Join(configDir, "goblin", "token"), nil
+}
+
+// ClientToken reads the token that clients send to the server. It is empty if the token file can't be located or
+// doesn't exist, since the server only requires a token when it creates the file
+func ClientToken() (string, error) {
+	fname, err := TokenFile()
+	if err != nil {
+		return "", nil
+	}
+
+	token, err := ReadToken(fname)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading API token: %w", err)
+	}
+
+	return token, nil
+}
+
+// LoadOrCreateToken reads the token from the file, or generates a new token and writes it to the file if it
+// doesn't exist yet. The file is created so only the current user can read it
+func LoadOrCreateToken(fname string) (string, error) {
+	token, err := ReadToken(fname)
+	if err == nil {
+		return token, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	b := make([]byte, tokenBytes)
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("error generating token: %w", err)
+	}
+	token = hex.EncodeToString(b)
+
+	err = os.MkdirAll(filepath.Dir(fname), 0o700)
+	if err != nil {
+		return "", fmt.Errorf("error creating token directory: %w", err)
+	}
+
+	err = os.WriteFile(fname, []byte(token+"\n"), 0o600)
+	if err != nil {
+		return "", fmt.Errorf("error writing token file: %w", err)
+	}
+
+	return token, nil
+}
+
+// ReadToken reads the token from the file. An error is returned if other users are able to read the file
+func ReadToken(fname string) (string, error) {
+	info, err := os.Stat(fname)
+	if err != nil {
+		return "", err
+	}
+
+	if info.Mode().Perm()&0o077 != 0 {
+		return "", errors.NewUserFixableError(
+			fmt.Errorf("token file %q is accessible by other users", fname),
+			fmt.Sprintf(insecureTokenFileInstruction, fname),
+		)
+	}
+
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return "", fmt.Errorf("error reading token file: %w", err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %q is empty", fname)
+	}
+
+	return token, nil
+}
+
+// SetToken adds the token to the request's Authorization header
+func SetToken(r *http.Request, token string) {
+	if token != "" {
+		r.Header.Set("Authorization", Authorization(token))
+	}
+}
+
+// Authorization is the value of the Authorization header for the token
+func Authorization(token string) string {
+	return "Bearer " + token
+}
+
+// Authenticated checks that the request has the token in its Authorization header or token query param
+func Authenticated(r *http.Request, token string) bool {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return validToken(r.URL.Query().Get(TokenQueryParam), token)
+	}
+	return ValidAuthorization(header, token)
+}
+
+// ValidAuthorization checks that the value of an Authorization header has the token
+func ValidAuthorization(header, token string) bool {
+	got, ok := strings.CutPrefix(header, "Bearer ")
+	return ok && validToken(got, token)
+}
+
+func validToken(got, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
@@ -0,0 +1,104 @@
+//go:build unix
+
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+func TestClientToken(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup returns the value for GOBLIN_TOKEN_FILE
+		setup         func(t *testing.T, dir string) string
+		expectedToken string
+		expectErr     bool
+	}{
+		{
+			"NoConfigDir",
+			func(t *testing.T, _ string) string {
+				t.Setenv("HOME", "")
+				t.Setenv("XDG_CONFIG_HOME", "")
+				return ""
+			},
+			"",
+			false,
+		},
+		{
+			"FileDoesNotExist",
+			func(_ *testing.T, dir string) string {
+				return filepath.Join(dir, "token")
+			},
+			"",
+			false,
+		},
+		{
+			"DefaultFileDoesNotExist",
+			func(t *testing.T, dir string) string {
+				t.Setenv("XDG_CONFIG_HOME", dir)
+				return ""
+			},
+			"",
+			false,
+		},
+		{
+			"ReadsToken",
+			func(t *testing.T, dir string) string {
+				fname := filepath.Join(dir, "token")
+				writeFile(t, fname, "abc123\n", 0o600)
+				return fname
+			},
+			"abc123",
+			false,
+		},
+		{
+			"InsecurePermissions",
+			func(t *testing.T, dir string) string {
+				fname := filepath.Join(dir, "token")
+				writeFile(t, fname, "abc123\n", 0o644)
+				return fname
+			},
+			"",
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(TokenFileEnvVar, tt.setup(t, t.TempDir()))
+
+			token, err := ClientToken()
+			if tt.expectErr {
+				var userErr errors.UserFixableError
+				if !errors.As(err, &userErr) {
+					t.Errorf("expected UserFixableError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if token != tt.expectedToken {
+				t.Errorf("expected token %q, got %q", tt.expectedToken, token)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, fname, contents string, perm os.FileMode) {
+	t.Helper()
+
+	err := os.WriteFile(fname, []byte(contents), perm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the umask might have removed permissions
+	err = os.Chmod(fname, perm)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
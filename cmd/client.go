@@ -3,10 +3,12 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
+	"time"
 
 	"github.com/calvinmclean/goblin/dns"
+	"github.com/calvinmclean/goblin/errors"
 
 	"github.com/urfave/cli/v3"
 )
@@ -17,6 +19,7 @@ var ClientCmd = &cli.Command{
 	Action:      runClient,
 	Flags: []cli.Flag{
 		portFlag,
+		socketFlag,
 		&cli.StringFlag{
 			Name:        "subdomain",
 			Aliases:     []string{"d"},
@@ -28,7 +31,7 @@ var ClientCmd = &cli.Command{
 }
 
 func runClient(ctx context.Context, c *cli.Command) error {
-	client, err := dns.NewHTTPClient(net.JoinHostPort(defaultAddr, serverPort))
+	client, err := newClient()
 	if err != nil {
 		return fmt.Errorf("error creating client: %w", err)
 	}
@@ -37,9 +40,39 @@ func runClient(ctx context.Context, c *cli.Command) error {
 	if err != nil {
 		return fmt.Errorf("error getting IP: %w", err)
 	}
-	log.Printf("Got IP: %s", ip)
+	slog.Info("got IP", "subdomain", subdomain, "ip", ip)
 
 	<-ctx.Done()
 
 	return err
 }
+
+// socketDialTimeout is how long to wait when checking if the server is listening on the default socket
+const socketDialTimeout = time.Second
+
+// newClient creates a client for the server, which uses the API token if it exists
+func newClient() (dns.Client, error) {
+	client, err := dns.NewHTTPClient(clientAddr())
+	if err != nil {
+		errors.PrintUserFixableErrorInstruction(err)
+		return dns.Client{}, err
+	}
+	return client, nil
+}
+
+// clientAddr is the Unix socket if it is configured or the server is listening on the default socket, otherwise
+// it uses serverPort
+func clientAddr() string {
+	if socketPath != "" {
+		return dns.UnixSocketAddr(socketPath)
+	}
+
+	// the default socket is dialed instead of checking if it exists since a server that crashed leaves it behind
+	conn, err := net.DialTimeout("unix", dns.DefaultSocketPath(), socketDialTimeout)
+	if err == nil {
+		conn.Close()
+		return dns.UnixSocketAddr(dns.DefaultSocketPath())
+	}
+
+	return net.JoinHostPort(defaultAddr, serverPort)
+}
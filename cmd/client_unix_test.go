@@ -0,0 +1,91 @@
+//go:build unix
+
+package cmd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/calvinmclean/goblin/dns"
+)
+
+func TestClientAddr(t *testing.T) {
+	tests := []struct {
+		name string
+		// socket is the state of the default socket: "missing", "stale", or "listening"
+		socket     string
+		socketFlag bool
+		expected   func(defaultSocket string) string
+	}{
+		{
+			"NoSocket",
+			"missing",
+			false,
+			func(string) string { return "127.0.0.1:8080" },
+		},
+		{
+			"StaleSocket",
+			"stale",
+			false,
+			func(string) string { return "127.0.0.1:8080" },
+		},
+		{
+			"ListeningSocket",
+			"listening",
+			false,
+			dns.UnixSocketAddr,
+		},
+		{
+			"SocketFlag",
+			"stale",
+			true,
+			func(string) string { return dns.UnixSocketAddr("/path/to/goblin.sock") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Unix socket paths have a short length limit, so t.TempDir might be too long
+			dir, err := os.MkdirTemp("", "goblin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.RemoveAll(dir) })
+			t.Setenv("XDG_RUNTIME_DIR", dir)
+
+			originalSocketPath, originalServerPort := socketPath, serverPort
+			t.Cleanup(func() { socketPath, serverPort = originalSocketPath, originalServerPort })
+			socketPath, serverPort = "", "8080"
+			if tt.socketFlag {
+				socketPath = "/path/to/goblin.sock"
+			}
+
+			defaultSocket := dns.DefaultSocketPath()
+			err = os.MkdirAll(filepath.Dir(defaultSocket), 0o700)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.socket != "missing" {
+				l, err := net.ListenUnix("unix", &net.UnixAddr{Name: defaultSocket, Net: "unix"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { l.Close() })
+
+				// a server that crashed leaves the socket file behind
+				if tt.socket == "stale" {
+					l.SetUnlinkOnClose(false)
+					l.Close()
+				}
+			}
+
+			actual := clientAddr()
+			if actual != tt.expected(defaultSocket) {
+				t.Errorf("expected %q, got %q", tt.expected(defaultSocket), actual)
+			}
+		})
+	}
+}
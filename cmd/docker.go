@@ -2,120 +2,265 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"log"
-	"net"
-	"net/http"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/calvinmclean/goblin/dns"
+	"github.com/calvinmclean/goblin/containers"
 	"github.com/calvinmclean/goblin/errors"
 
 	"github.com/urfave/cli/v3"
 )
 
-const defaultDockerSocket = "/var/run/docker.sock"
-
 var (
 	dockerSocketEnvVar = cli.EnvVar("DOCKER_SOCK")
 
-	dockerContainer, dockerSocket string
-	DockerCmd                     = &cli.Command{
+	dockerSocketFlag = &cli.StringFlag{
+		Name:        "socket",
+		Usage:       "path to the Docker or Podman socket",
+		DefaultText: "detected from DOCKER_HOST or the default Docker and Podman sockets",
+		TakesFile:   true,
+		Destination: &dockerSocket,
+		Sources:     cli.ValueSourceChain{Chain: []cli.ValueSource{dockerSocketEnvVar}},
+	}
+	// serverDockerSocketFlag is the dockerSocketFlag for the server, which uses --socket for its API
+	serverDockerSocketFlag = &cli.StringFlag{
+		Name:        "docker-socket",
+		Usage:       "path to the Docker or Podman socket used to register containers from the dashboard",
+		DefaultText: dockerSocketFlag.DefaultText,
+		TakesFile:   true,
+		Destination: &dockerSocket,
+		Sources:     dockerSocketFlag.Sources,
+	}
+	runtimeFlag = &cli.StringFlag{
+		Name:        "runtime",
+		Usage:       "container runtime: " + containers.RuntimeDocker + " or " + containers.RuntimePodman,
+		DefaultText: "detected from the socket",
+		Destination: &containerRuntime,
+		Sources:     cli.ValueSourceChain{Chain: []cli.ValueSource{cli.EnvVar("GOBLIN_CONTAINER_RUNTIME")}},
+		Validator: func(v string) error {
+			_, err := containers.NewRuntime(v, "")
+			return err
+		},
+	}
+	forwardFlag = &cli.BoolFlag{
+		Name: "forward",
+		Usage: "always allocate a Goblin IP and forward the container's published ports from it. This is done" +
+			" automatically when the container's IP isn't reachable from the host, like with Docker Desktop",
+		Destination: &forwardPorts,
+	}
+	dockerNetworkFlag = &cli.StringFlag{
+		Name:        "network",
+		Usage:       "network to get container IPs from when containers are connected to more than one",
+		DefaultText: "first network by name",
+		Destination: &dockerNetwork,
+		Sources:     cli.ValueSourceChain{Chain: []cli.ValueSource{cli.EnvVar("GOBLIN_DOCKER_NETWORK")}},
+	}
+
+	errMissingContainer = errors.New("missing required flag: --container/-c or --compose-project")
+
+	dockerContainer, dockerSocket, containerRuntime, dockerNetwork, composeProject string
+	watchAllContainers, includeProject, forwardPorts                               bool
+	DockerCmd                                                                      = &cli.Command{
 		Name:        "docker",
-		Description: "register a docker container with a subdomain",
+		Aliases:     []string{"container"},
+		Description: "register a Docker or Podman container or the services of a compose project with subdomains",
 		Action:      runRegisterDocker,
 		Flags: []cli.Flag{
 			portFlag,
+			apiSocketFlag,
 			&cli.StringFlag{
 				Name:        "subdomain",
 				Aliases:     []string{"d"},
 				Usage:       "subdomain name",
 				DefaultText: "docker container name (if using --container/-c)",
 				Destination: &subdomain,
+				Local:       true,
 			},
 			&cli.StringFlag{
 				Name:        "container",
 				Aliases:     []string{"c"},
-				Usage:       "name of a docker container running locally",
+				Usage:       "name of a container running locally",
 				Destination: &dockerContainer,
-				Required:    true,
+				Local:       true,
 			},
 			&cli.StringFlag{
-				Name:        "socket",
-				Usage:       "path to the docker socket",
-				Destination: &dockerSocket,
-				Value:       defaultDockerSocket,
-				Sources:     cli.ValueSourceChain{Chain: []cli.ValueSource{dockerSocketEnvVar}},
+				Name:        "compose-project",
+				Usage:       "name of a docker compose project to register each service of using the service name as its subdomain",
+				Destination: &composeProject,
+				Local:       true,
+			},
+			&cli.BoolFlag{
+				Name:        "include-project",
+				Usage:       "include the project name in compose service subdomains like service.project",
+				Destination: &includeProject,
+				Local:       true,
+			},
+			dockerSocketFlag,
+			runtimeFlag,
+			dockerNetworkFlag,
+			forwardFlag,
+			expireFlag,
+		},
+		Commands: []*cli.Command{DockerWatchCmd},
+	}
+	DockerWatchCmd = &cli.Command{
+		Name: "watch",
+		Description: "register running containers that have the " + containers.SubdomainLabel + " label and keep" +
+			" their routes updated as containers start and stop",
+		Action: runDockerWatch,
+		Flags: []cli.Flag{
+			portFlag,
+			apiSocketFlag,
+			dockerSocketFlag,
+			runtimeFlag,
+			dockerNetworkFlag,
+			forwardFlag,
+			&cli.BoolFlag{
+				Name:        "all",
+				Usage:       "also register containers without the " + containers.SubdomainLabel + " label using their names",
+				Destination: &watchAllContainers,
 			},
 		},
 	}
 )
 
 func runRegisterDocker(ctx context.Context, c *cli.Command) error {
+	switch {
+	case dockerContainer == "" && composeProject == "":
+		return errMissingContainer
+	case dockerContainer != "" && composeProject != "":
+		return errors.New("use only one of --container/-c and --compose-project")
+	case composeProject != "" && subdomain != "":
+		return errors.New("--subdomain/-d can't be used with --compose-project")
+	}
+
+	runtime, err := newRuntime(ctx)
+	if err != nil {
+		errors.PrintUserFixableErrorInstruction(err)
+		return err
+	}
+
+	if composeProject != "" {
+		return registerComposeProject(ctx, runtime)
+	}
+
 	if subdomain == "" {
 		subdomain = dockerContainer
 	}
 
-	containerIP, err := getContainerIP(dockerContainer)
+	container, err := runtime.RunningContainer(ctx, dockerContainer)
 	if err != nil {
 		return fmt.Errorf("error getting IP for container: %w", err)
 	}
 
-	client, err := dns.NewHTTPClient(net.JoinHostPort(defaultAddr, serverPort))
+	return registerContainers(ctx, map[string]containers.Container{subdomain: container})
+}
+
+// registerComposeProject registers a route for each service in the compose project
+func registerComposeProject(ctx context.Context, runtime containers.Runtime) error {
+	services, err := runtime.ComposeServices(ctx, composeProject)
 	if err != nil {
-		return fmt.Errorf("error creating client: %w", err)
+		return fmt.Errorf("error getting compose services: %w", err)
 	}
 
-	err = client.RegisterFallback(subdomain, containerIP)
-	if err != nil {
-		return fmt.Errorf("error registering docker container: %w", err)
+	toRegister := map[string]containers.Container{}
+	for _, service := range services {
+		if service.IP == "" {
+			return fmt.Errorf("no IP address found for compose service %q", service.Service)
+		}
+		toRegister[service.Subdomain(includeProject)] = service.Container
 	}
-	log.Print("registered docker container")
 
-	return err
+	return registerContainers(ctx, toRegister)
 }
 
-func getContainerIP(containerName string) (string, error) {
-	url := fmt.Sprintf("http://localhost/containers/%s/json", containerName)
-	req, err := http.NewRequest(http.MethodGet, url, http.NoBody)
+// registerContainers registers routes to the containers by subdomain. If any container's ports are forwarded,
+// it keeps running until interrupted or the routes expire
+func registerContainers(ctx context.Context, toRegister map[string]containers.Container) error {
+	client, err := newClient()
 	if err != nil {
-		return "", fmt.Errorf("error creating request: %w", err)
+		return fmt.Errorf("error creating client: %w", err)
 	}
 
-	client := &http.Client{
-		Transport: &http.Transport{
-			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-				return net.Dial("unix", dockerSocket)
-			},
-		},
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if expiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, expiresIn)
+		defer cancel()
+	}
+
+	var forwarding bool
+	for subdomain, c := range toRegister {
+		forwarded, err := containers.Register(ctx, client, c, subdomain, containers.RegisterOptions{
+			ExpiresIn: expiresIn,
+			Forward:   forwardPorts,
+		})
+		if err != nil {
+			return fmt.Errorf("error registering container %q: %w", c.Name, err)
+		}
+		slog.Info("registered container", "subdomain", subdomain, "container", c.Name, "forwarded", forwarded)
+
+		forwarding = forwarding || forwarded
+	}
+
+	if forwarding {
+		slog.Info("forwarding published ports until interrupted")
+		<-ctx.Done()
 	}
 
-	resp, err := client.Do(req)
+	return nil
+}
+
+func runDockerWatch(ctx context.Context, c *cli.Command) error {
+	runtime, err := newRuntime(ctx)
 	if err != nil {
-		return "", fmt.Errorf("error making request: %w", err)
+		errors.PrintUserFixableErrorInstruction(err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	client, err := newClient()
 	if err != nil {
-		return "", fmt.Errorf("error reading response body: %w", err)
+		return fmt.Errorf("error creating client: %w", err)
 	}
 
-	var containerData struct {
-		NetworkSettings struct {
-			Networks map[string]struct {
-				IPAddress string `json:"IPAddress"`
-			} `json:"Networks"`
-		} `json:"NetworkSettings"`
+	// routes are removed when the watcher stops, so stop on interrupt instead of exiting immediately
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	watcher := containers.NewWatcher(runtime, client, slog.Default())
+	if watchAllContainers {
+		watcher = watcher.WithAllContainers()
 	}
-	if err := json.Unmarshal(body, &containerData); err != nil {
-		return "", fmt.Errorf("error parsing response: %w", err)
+	if forwardPorts {
+		watcher = watcher.WithForwarding()
 	}
 
-	for _, network := range containerData.NetworkSettings.Networks {
-		return network.IPAddress, nil
+	slog.Info("watching containers", "runtime", runtime.Name())
+	err = watcher.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("error watching containers: %w", err)
+	}
+
+	return nil
+}
+
+// newRuntime creates the container runtime from the flags, or detects it if it isn't set
+func newRuntime(ctx context.Context) (containers.Runtime, error) {
+	var runtime containers.Runtime
+	var err error
+	if containerRuntime != "" {
+		runtime, err = containers.NewRuntime(containerRuntime, dockerSocket)
+	} else {
+		runtime, err = containers.DetectRuntime(ctx, dockerSocket)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating container runtime: %w", err)
 	}
 
-	return "", errors.New("no IP address found for container")
+	return runtime.WithNetwork(dockerNetwork), nil
 }
@@ -3,12 +3,13 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
 	"log/slog"
 	"net"
 	"time"
 
+	"github.com/calvinmclean/goblin/containers"
 	"github.com/calvinmclean/goblin/dns"
+	"github.com/calvinmclean/goblin/plugins"
 	"github.com/calvinmclean/goblin/server"
 
 	"github.com/urfave/cli/v3"
@@ -21,18 +22,25 @@ var ExampleCmd = &cli.Command{
 }
 
 func runExample(ctx context.Context, c *cli.Command) error {
-	slog.SetLogLoggerLevel(slog.LevelDebug)
-
 	dnsMgr, err := dns.New(dns.Config{
 		Domain:  "goblin",
 		Address: net.JoinHostPort(defaultAddr, defaultDNSPort),
+		Logger:  slog.Default(),
 	})
 	if err != nil {
 		return fmt.Errorf("error creating DNS Manager: %w", err)
 	}
 
+	env := plugins.EnvConfig{
+		Domain: dnsMgr.Domain,
+		Lookup: func(_ context.Context, subdomain string) (string, error) {
+			lookup, err := dnsMgr.Lookup(subdomain)
+			return lookup.IP, err
+		},
+	}
+
 	go func() {
-		err := runPlugin(ctx, dnsMgr, "./example-plugins/helloworld/cmd/hello/hello.so", "helloworld", 0)
+		err := runPlugin(ctx, dnsMgr, "./example-plugins/helloworld/cmd/hello/hello.so", "helloworld", env, 0)
 		if err != nil {
 			panic(err)
 		}
@@ -41,7 +49,7 @@ func runExample(ctx context.Context, c *cli.Command) error {
 	go func() {
 		time.Sleep(5 * time.Second)
 
-		err := runPlugin(ctx, dnsMgr, "./example-plugins/helloworld/cmd/howdy/howdy.so", "howdy", 0)
+		err := runPlugin(ctx, dnsMgr, "./example-plugins/helloworld/cmd/howdy/howdy.so", "howdy", env, 0)
 		if err != nil {
 			panic(err)
 		}
@@ -50,16 +58,16 @@ func runExample(ctx context.Context, c *cli.Command) error {
 	go func() {
 		time.Sleep(15 * time.Second)
 
-		err := runPlugin(ctx, dnsMgr, "./example-plugins/helloworld/cmd/howdy/howdy.so", "howdynew", 0)
+		err := runPlugin(ctx, dnsMgr, "./example-plugins/helloworld/cmd/howdy/howdy.so", "howdynew", env, 0)
 		if err != nil {
 			panic(err)
 		}
 	}()
 
-	server := server.New(dnsMgr, net.JoinHostPort(defaultAddr, defaultServerPort))
+	server := server.New(dnsMgr, net.JoinHostPort(defaultAddr, defaultServerPort), containers.NewDocker(containers.DefaultDockerSocket), slog.Default())
 	err = server.Run(ctx)
 	if err != nil {
-		log.Fatalf("error running GRPC server: %v", err)
+		return fmt.Errorf("error running server: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/calvinmclean/goblin/dns"
+
+	"github.com/urfave/cli/v3"
+)
+
+var (
+	jsonOutput, watchList bool
+	watchInterval         time.Duration
+	ListCmd               = &cli.Command{
+		Name:        "list",
+		Aliases:     []string{"status"},
+		Description: "show active and released IP allocations and fallback routes",
+		Action:      runList,
+		Flags: []cli.Flag{
+			portFlag,
+			socketFlag,
+			&cli.BoolFlag{
+				Name:        "json",
+				Usage:       "print status as JSON",
+				Destination: &jsonOutput,
+			},
+			&cli.BoolFlag{
+				Name:        "watch",
+				Aliases:     []string{"w"},
+				Usage:       "keep printing the status until stopped",
+				Destination: &watchList,
+			},
+			&cli.DurationFlag{
+				Name:        "interval",
+				Value:       2 * time.Second,
+				Usage:       "how often to refresh the status in watch mode",
+				Destination: &watchInterval,
+			},
+		},
+	}
+)
+
+func runList(ctx context.Context, c *cli.Command) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error creating client: %w", err)
+	}
+
+	if !watchList {
+		return printStatus(ctx, client, os.Stdout)
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		if !jsonOutput {
+			// clear the terminal so the table is redrawn in place
+			fmt.Print("\033[H\033[2J")
+		}
+
+		err := printStatus(ctx, client, os.Stdout)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func printStatus(ctx context.Context, client dns.Client, w io.Writer) error {
+	status, err := client.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting status: %w", err)
+	}
+
+	if jsonOutput {
+		return json.NewEncoder(w).Encode(status)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "ACTIVE\nSUBDOMAIN\tIP\tALLOCATED")
+	for _, rec := range status.Active {
+		ip := rec.IP
+		if rec.Pending {
+			ip += " (pending)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", rec.Subdomain, ip, formatTime(rec.AllocatedAt))
+	}
+
+	fmt.Fprintln(tw, "\nRELEASED\nSUBDOMAIN\tIP\tREMOVED")
+	for _, rec := range status.Released {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", rec.Subdomain, rec.IP, formatTime(*rec.RemovedAt))
+	}
+
+	fmt.Fprintln(tw, "\nFALLBACKS\nSUBDOMAIN\tTARGET\tIP\tSOURCE\tEXPIRES")
+	for _, fallback := range status.Fallbacks {
+		ip := fallback.IP
+		if fallback.Error != "" {
+			ip = "error: " + fallback.Error
+		}
+		expires := "never"
+		if fallback.ExpiresAt != nil {
+			expires = fmt.Sprintf("in %s", time.Until(*fallback.ExpiresAt).Round(time.Second))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", fallback.Subdomain, fallback.Route.Target, ip, fallback.Source, expires)
+	}
+
+	return tw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	return fmt.Sprintf("%s (%s ago)", t.Local().Format(time.DateTime), time.Since(t).Round(time.Second))
+}
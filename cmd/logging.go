@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/urfave/cli/v3"
+)
+
+var (
+	logLevel, logFormat string
+
+	// LoggingFlags are used by the root command so they apply to all sub-commands
+	LoggingFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "log-level",
+			Value:       "info",
+			Usage:       "minimum level of logs to print: debug, info, warn, or error",
+			Destination: &logLevel,
+			Sources:     cli.ValueSourceChain{Chain: []cli.ValueSource{cli.EnvVar("GOBLIN_LOG_LEVEL")}},
+			Validator: func(v string) error {
+				var level slog.Level
+				return level.UnmarshalText([]byte(v))
+			},
+		},
+		&cli.StringFlag{
+			Name:        "log-format",
+			Value:       "text",
+			Usage:       "format of logs: text or json",
+			Destination: &logFormat,
+			Sources:     cli.ValueSourceChain{Chain: []cli.ValueSource{cli.EnvVar("GOBLIN_LOG_FORMAT")}},
+			Validator: func(v string) error {
+				if v != "text" && v != "json" {
+					return fmt.Errorf("unsupported log format: %q", v)
+				}
+				return nil
+			},
+		},
+	}
+)
+
+// SetupLogging is used as the root command's Before function to configure the default logger from flags
+func SetupLogging(ctx context.Context, c *cli.Command) (context.Context, error) {
+	slog.SetDefault(newLogger(os.Stderr))
+	return ctx, nil
+}
+
+// newLogger creates a logger that writes to w using the level and format from flags
+func newLogger(w io.Writer) *slog.Logger {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(logLevel))
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	if logFormat == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/calvinmclean/goblin/dns"
+)
+
+// forwardedLineBuffer is the number of lines that can wait to be sent to the server before they are dropped
+const forwardedLineBuffer = 1000
+
+// lineForwarder writes logs to out and also sends each line to be streamed to the server. Lines are dropped
+// instead of blocking if the server is not keeping up
+type lineForwarder struct {
+	out io.Writer
+
+	mu     sync.Mutex
+	lines  chan string
+	closed bool
+}
+
+func (f *lineForwarder) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimSuffix(p, []byte("\n")), []byte("\n")) {
+		f.forward(string(line))
+	}
+
+	return f.out.Write(p)
+}
+
+// forward sends a line to the server without writing it to out
+func (f *lineForwarder) forward(line string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return
+	}
+
+	select {
+	case f.lines <- line:
+	default:
+	}
+}
+
+func (f *lineForwarder) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	close(f.lines)
+}
+
+// forwardOutput sets the default logger to include the subdomain and send logs to the server so they can be
+// viewed in the dashboard. Anything the plugin writes directly to stdout or stderr, or with the standard log
+// package, is captured and sent to the server too. It is still printed as-is to the original stdout or stderr
+// so it isn't filtered by the log level. The returned function restores the original outputs and waits for the
+// remaining output to be printed
+func forwardOutput(ctx context.Context, client dns.Client, subdomain string) (func(), error) {
+	outReader, outWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("error creating pipe: %w", err)
+	}
+
+	errReader, errWriter, err := os.Pipe()
+	if err != nil {
+		outReader.Close()
+		outWriter.Close()
+		return nil, fmt.Errorf("error creating pipe: %w", err)
+	}
+
+	stdout, stderr := os.Stdout, os.Stderr
+	defaultLogger := slog.Default()
+
+	forwarder := &lineForwarder{out: stderr, lines: make(chan string, forwardedLineBuffer)}
+	slog.SetDefault(newLogger(forwarder).With("subdomain", subdomain))
+
+	// slog.SetDefault sends output from the standard log package to slog, so this is set after it to keep the
+	// plugin's log output unchanged
+	logOutput, logFlags := log.Writer(), log.Flags()
+	log.SetOutput(errWriter)
+	log.SetFlags(log.LstdFlags)
+
+	os.Stdout, os.Stderr = outWriter, errWriter
+
+	streamReader, streamWriter := io.Pipe()
+
+	go func() {
+		err := client.StreamLogs(ctx, subdomain, streamReader)
+		if err != nil {
+			fmt.Fprintf(stderr, "error sending logs to server: %v\n", err)
+		}
+		// keep reading so output is not blocked if the server is unavailable
+		_ = streamReader.CloseWithError(io.ErrClosedPipe)
+	}()
+
+	go func() {
+		defer streamWriter.Close()
+		for line := range forwarder.lines {
+			_, _ = fmt.Fprintln(streamWriter, line)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	copyLines := func(r io.Reader, out io.Writer) {
+		defer wg.Done()
+
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			fmt.Fprintln(out, scanner.Text())
+			forwarder.forward(scanner.Text())
+		}
+	}
+
+	wg.Add(2)
+	go copyLines(outReader, stdout)
+	go copyLines(errReader, stderr)
+
+	return func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		slog.SetDefault(defaultLogger)
+		log.SetOutput(logOutput)
+		log.SetFlags(logFlags)
+
+		outWriter.Close()
+		errWriter.Close()
+		wg.Wait()
+		outReader.Close()
+		errReader.Close()
+		forwarder.close()
+	}, nil
+}
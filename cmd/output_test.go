@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/calvinmclean/goblin/dns"
+)
+
+func TestForwardOutput(t *testing.T) {
+	streamed := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/logs/app" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+
+		data, _ := io.ReadAll(r.Body)
+		streamed <- string(data)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := dns.NewHTTPClient(strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	originalStdout, originalStderr, originalLevel := os.Stdout, os.Stderr, logLevel
+	os.Stdout, os.Stderr, logLevel = stdout, stderr, "warn"
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr, logLevel = originalStdout, originalStderr, originalLevel
+	})
+
+	restore, err := forwardOutput(context.Background(), client, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fmt.Fprintln(os.Stdout, "stdout line")
+	fmt.Fprintln(os.Stderr, "stderr line")
+	log.Print("log line")
+	slog.Info("filtered")
+	slog.Warn("warning")
+
+	restore()
+
+	if os.Stdout != stdout || os.Stderr != stderr {
+		t.Error("expected stdout and stderr to be restored")
+	}
+
+	out, err := os.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "stdout line\n" {
+		t.Errorf("expected only raw stdout output, got %q", out)
+	}
+
+	errOut, err := os.ReadFile(stderr.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"stderr line\n", "log line\n", "msg=warning subdomain=app"} {
+		if !strings.Contains(string(errOut), expected) {
+			t.Errorf("expected stderr to contain %q, got %q", expected, errOut)
+		}
+	}
+	if strings.Contains(string(errOut), "filtered") {
+		t.Errorf("expected info log to be filtered, got %q", errOut)
+	}
+	if strings.Contains(string(errOut), "component=plugin") {
+		t.Errorf("expected output to not be logged, got %q", errOut)
+	}
+
+	lines := <-streamed
+	for _, expected := range []string{"stdout line\n", "stderr line\n", "log line\n", "msg=warning"} {
+		if !strings.Contains(lines, expected) {
+			t.Errorf("expected streamed logs to contain %q, got %q", expected, lines)
+		}
+	}
+}
@@ -3,22 +3,29 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
-	"net"
-
-	"github.com/calvinmclean/goblin/dns"
+	"log/slog"
+	"time"
 
 	"github.com/urfave/cli/v3"
 )
 
 var (
+	expireFlag = &cli.DurationFlag{
+		Name:        "expire",
+		Usage:       "remove the route automatically after this duration",
+		DefaultText: "never",
+		Destination: &expiresIn,
+	}
+
 	address     string
+	expiresIn   time.Duration
 	RegisterCmd = &cli.Command{
 		Name:        "register",
 		Description: "register a fallback route with the server",
 		Action:      runRegister,
 		Flags: []cli.Flag{
 			portFlag,
+			socketFlag,
 			&cli.StringFlag{
 				Name:        "subdomain",
 				Aliases:     []string{"d"},
@@ -33,21 +40,22 @@ var (
 				Destination: &address,
 				Required:    true,
 			},
+			expireFlag,
 		},
 	}
 )
 
 func runRegister(ctx context.Context, c *cli.Command) error {
-	client, err := dns.NewHTTPClient(net.JoinHostPort(defaultAddr, serverPort))
+	client, err := newClient()
 	if err != nil {
 		return fmt.Errorf("error creating client: %w", err)
 	}
 
-	err = client.RegisterFallback(subdomain, address)
+	err = client.RegisterFallback(ctx, subdomain, address, expiresIn)
 	if err != nil {
 		return fmt.Errorf("error registering fallback: %w", err)
 	}
-	log.Print("registered fallback")
+	slog.Info("registered fallback", "subdomain", subdomain)
 
 	return err
 }
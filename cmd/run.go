@@ -3,20 +3,26 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
-	"net"
+	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/calvinmclean/goblin/dns"
 	"github.com/calvinmclean/goblin/errors"
 	"github.com/calvinmclean/goblin/plugins"
+	"github.com/calvinmclean/goblin/watch"
 
 	"github.com/urfave/cli/v3"
 )
 
+// reloadPollInterval is how often the source is checked for changes with --watch
+const reloadPollInterval = 500 * time.Millisecond
+
 var (
 	portFlag = &cli.StringFlag{
 		Name:        "port",
@@ -25,18 +31,40 @@ var (
 		Destination: &serverPort,
 		Sources:     cli.ValueSourceChain{Chain: []cli.ValueSource{portEnvVar}},
 	}
+	socketFlag = &cli.StringFlag{
+		Name:        "socket",
+		Usage:       "path to the Unix socket of the API server. Clients use the default socket if the server is listening on it, otherwise the port",
+		TakesFile:   true,
+		Destination: &socketPath,
+		Sources:     cli.ValueSourceChain{Chain: []cli.ValueSource{cli.EnvVar("GOBLIN_SOCKET")}},
+	}
+	// apiSocketFlag is the socketFlag for commands that already use --socket for another socket
+	apiSocketFlag = &cli.StringFlag{
+		Name:        "api-socket",
+		Usage:       socketFlag.Usage,
+		TakesFile:   true,
+		Destination: &socketPath,
+		Sources:     socketFlag.Sources,
+	}
 
-	pluginFilename, subdomain, ipEnvVar string
-	isDir                               bool
-	RunCmd                              = &cli.Command{
+	socketPath                                        string
+	execArgs                                          []string
+	pluginFilename, execFilename, subdomain, ipEnvVar string
+	buildFlags, buildTags                             string
+	isDir, subprocess, watchSource                    bool
+	readyPort, listenPort                             int64
+	tlsCert, tlsKey                                   string
+	readyPath, readyCommand, restartPolicy            string
+	readyProbe                                        plugins.Probe
+	RunCmd                                            = &cli.Command{
 		Name:        "run",
-		Description: "build and run a plugin",
+		Description: "build and run a plugin, or run an executable with --exec or --subprocess",
+		ArgsUsage:   "[-- args for the executable]",
 		Action:      runPluginCmd,
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:        "plugin",
 				Aliases:     []string{"p"},
-				Required:    true,
 				TakesFile:   true,
 				Usage:       "filename for *.so plugin or directory for building it",
 				Destination: &pluginFilename,
@@ -58,34 +86,483 @@ var (
 					return nil
 				},
 			},
+			&cli.StringFlag{
+				Name:    "exec",
+				Aliases: []string{"x"},
+				Usage: "executable to run as a child process instead of a plugin. Arguments after -- are passed to it" +
+					" and can use the IP with a template like --addr={{.IP}}:8080",
+				TakesFile:   true,
+				Destination: &execFilename,
+			},
+			&cli.BoolFlag{
+				Name: "subprocess",
+				Usage: "build the plugin directory as a normal executable and run it as a child process instead of" +
+					" loading a plugin, so any main package works. Only main() runs, so plugins without one that" +
+					" calls Run can't be used. Arguments after -- are passed to it like with --exec",
+				Destination: &subprocess,
+			},
+			&cli.StringFlag{
+				Name:        "build-flags",
+				Usage:       "space-separated flags for go build with --subprocess, like -race",
+				Destination: &buildFlags,
+			},
+			&cli.StringFlag{
+				Name:        "tags",
+				Usage:       "comma-separated build tags for go build with --subprocess",
+				Destination: &buildTags,
+			},
+			&cli.BoolFlag{
+				Name:    "watch",
+				Aliases: []string{"w"},
+				Usage: "rebuild and restart on the same IP when the source changes. Go plugins can't be unloaded, so" +
+					" a directory is built and run with --subprocess, which runs main() instead of Run and needs a" +
+					" main function that gets the IP from " + plugins.DefaultIPEnvVar + " or --env. With --exec, it restarts when the executable changes",
+				Destination: &watchSource,
+			},
+			&cli.IntFlag{
+				Name: "ready-port",
+				Usage: "port to check with a TCP connection before DNS switches from the fallback route to the" +
+					" local IP. Use --ready-path to check it with an HTTP GET request instead",
+				Destination: &readyPort,
+			},
+			&cli.StringFlag{
+				Name:        "ready-path",
+				Usage:       "HTTP path to check at --ready-port, like /healthz. Any 2xx or 3xx status is ready",
+				Destination: &readyPath,
+			},
+			&cli.StringFlag{
+				Name: "ready-command",
+				Usage: "space-separated command to check if it's ready instead of --ready-port. It is ready when it" +
+					" exits successfully, and it can use the IP with a template like {{.IP}}",
+				Destination: &readyCommand,
+			},
+			&cli.StringFlag{
+				Name: "restart",
+				Usage: "when to run it again after it exits or panics: never, on-failure, or always. The IP is kept and" +
+					" DNS uses the fallback route until it's ready again. Restarts are delayed with backoff",
+				Value:       plugins.RestartNever,
+				Destination: &restartPolicy,
+				Validator: func(v string) error {
+					switch v {
+					case plugins.RestartNever, plugins.RestartOnFailure, plugins.RestartAlways:
+						return nil
+					}
+					return fmt.Errorf("invalid restart %q", v)
+				},
+			},
+			&cli.IntFlag{
+				Name:        "listen-port",
+				Usage:       "suggested port for plugins that use goblin.Env",
+				DefaultText: "8080",
+				Destination: &listenPort,
+			},
+			&cli.StringFlag{
+				Name:        "tls-cert",
+				Usage:       "certificate file for the TLS config in goblin.Env. Requires --tls-key",
+				TakesFile:   true,
+				Destination: &tlsCert,
+			},
+			&cli.StringFlag{
+				Name:        "tls-key",
+				Usage:       "key file for the TLS config in goblin.Env. Requires --tls-cert",
+				TakesFile:   true,
+				Destination: &tlsKey,
+			},
 			&cli.StringFlag{
 				Name:        "subdomain",
 				Aliases:     []string{"d"},
 				Usage:       "subdomain name",
-				DefaultText: "plugin filename (without .so)",
+				DefaultText: "plugin filename (without .so) or executable filename",
 				Destination: &subdomain,
 			},
 			&cli.StringFlag{
 				Name:    "env",
 				Aliases: []string{"e"},
 				Usage: "environment variable to communicate IP. Goblin will set this env var" +
-					" with the allocated IP and run your application's main() function",
+					" with the allocated IP and run your application's main() function. Executables" +
+					" use " + plugins.DefaultIPEnvVar + " by default",
 				Destination: &ipEnvVar,
 			},
 			portFlag,
+			socketFlag,
 		},
 	}
 )
 
 func runPluginCmd(ctx context.Context, c *cli.Command) error {
-	client, err := dns.NewHTTPClient(net.JoinHostPort(defaultAddr, serverPort))
+	if watchSource && isDir {
+		subprocess = true
+	}
+
+	switch {
+	case pluginFilename == "" && execFilename == "":
+		return errors.New("missing required flag: --plugin/-p or --exec/-x")
+	case pluginFilename != "" && execFilename != "":
+		return errors.New("use only one of --plugin/-p and --exec/-x")
+	case pluginFilename != "" && len(execArgs) > 0 && !subprocess:
+		return errors.New("arguments can only be used with --exec/-x or --subprocess")
+	case subprocess && !isDir:
+		return errors.New("--subprocess requires --plugin/-p to be a directory")
+	case (buildFlags != "" || buildTags != "") && !subprocess:
+		return errors.New("--build-flags and --tags can only be used with --subprocess")
+	case watchSource && execFilename == "" && !isDir:
+		return errors.New("--watch requires --plugin/-p to be a directory or --exec/-x")
+	case readyPath != "" && readyPort == 0:
+		return errors.New("--ready-path requires --ready-port")
+	case readyPort != 0 && readyCommand != "":
+		return errors.New("use only one of --ready-port and --ready-command")
+	case watchSource && restartPolicy != plugins.RestartNever:
+		return errors.New("--restart can't be used with --watch, which waits for changes after it exits")
+	case (listenPort != 0 || tlsCert != "" || tlsKey != "") && (pluginFilename == "" || subprocess):
+		return errors.New("--listen-port, --tls-cert, and --tls-key can only be used with plugins")
+	case (tlsCert == "") != (tlsKey == ""):
+		return errors.New("--tls-cert and --tls-key must be used together")
+	}
+
+	// this is also checked when building, but --watch would keep waiting for changes instead of exiting
+	if subprocess {
+		err := plugins.CheckMain(pluginFilename, buildTags)
+		if err != nil {
+			errors.PrintUserFixableErrorInstruction(err)
+			return err
+		}
+	}
+
+	// readyProbe stays nil if the --ready-* flags aren't used
+	if readyPort != 0 || readyCommand != "" {
+		var err error
+		readyProbe, err = plugins.NewProbe(plugins.ProbeConfig{
+			Port:    int(readyPort),
+			Path:    readyPath,
+			Command: strings.Fields(readyCommand),
+		})
+		if err != nil {
+			return fmt.Errorf("invalid readiness probe: %w", err)
+		}
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error creating client: %w", err)
+	}
+
+	if subdomain == "" {
+		subdomain = pluginSubdomain(pluginFilename)
+		if execFilename != "" {
+			subdomain = filepath.Base(execFilename)
+		}
+
+		_, err = dns.NormalizeSubdomain(subdomain)
+		if err != nil {
+			return fmt.Errorf("filename can't be used as the subdomain, use --subdomain to choose one: %w", err)
+		}
+	}
+
+	// send the plugin's output to the server so it can be viewed in the dashboard
+	restoreOutput, err := forwardOutput(ctx, client, subdomain)
 	if err != nil {
-		return fmt.Errorf("error creating GRPC Client: %w", err)
+		return fmt.Errorf("error forwarding output: %w", err)
+	}
+	defer restoreOutput()
+
+	if watchSource && execFilename != "" {
+		return runWatch(ctx, client, execFilename, subdomain, func() (string, error) {
+			return execFilename, nil
+		})
+	}
+
+	if watchSource {
+		return runWatch(ctx, client, pluginFilename, subdomain, buildExecutable)
+	}
+
+	if execFilename != "" {
+		return runExec(ctx, client, execFilename, execArgs, subdomain)
 	}
-	return runPlugin(ctx, client, pluginFilename, subdomain, 0)
+
+	if subprocess {
+		fname, err := buildExecutable()
+		if err != nil {
+			return err
+		}
+
+		return runExec(ctx, client, fname, execArgs, subdomain)
+	}
+
+	env, err := pluginEnv(ctx, client)
+	if err != nil {
+		return err
+	}
+	env.Port = int(listenPort)
+	if tlsCert != "" {
+		env.TLS, err = plugins.LoadTLSConfig(tlsCert, tlsKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return runPlugin(ctx, client, pluginFilename, subdomain, env, 0)
 }
 
-func runPlugin(ctx context.Context, dnsMgr plugins.IPGetter, fname, subdomain string, timeout time.Duration) error {
+// pluginEnv gets the domain from the server and uses it to look up other subdomains for plugins that use
+// goblin.Env
+func pluginEnv(ctx context.Context, client dns.Client) (plugins.EnvConfig, error) {
+	info, err := client.Info(ctx)
+	if err != nil {
+		return plugins.EnvConfig{}, fmt.Errorf("error getting server info: %w", err)
+	}
+
+	return plugins.EnvConfig{
+		Domain: info.Domain,
+		Lookup: func(ctx context.Context, subdomain string) (string, error) {
+			lookup, err := client.Lookup(ctx, subdomain)
+			return lookup.IP, err
+		},
+	}, nil
+}
+
+// runExec runs an executable as a child process with the IP allocated for as long as it runs
+func runExec(ctx context.Context, dnsMgr plugins.IPGetter, fname string, args []string, subdomain string) error {
+	run, err := plugins.Exec(fname, args, plugins.ExecOptions{IPEnvVar: ipEnvVar, ForwardSignals: true})
+	if err != nil {
+		errors.PrintUserFixableErrorInstruction(err)
+		return fmt.Errorf("error loading executable: %w", err)
+	}
+
+	slog.Info("starting process", "subdomain", subdomain, "executable", fname)
+	err = startRun(ctx, run, dnsMgr, subdomain)
+	if err != nil && !errors.Is(err, plugins.ErrStopped) {
+		return fmt.Errorf("error running process: %w", err)
+	}
+
+	slog.Info("stopped process", "subdomain", subdomain)
+	return nil
+}
+
+// startRun runs the plugin or process with the readiness probe and restart policy if they are used
+func startRun(ctx context.Context, run plugins.RunFunc, dnsMgr plugins.IPGetter, subdomain string) error {
+	if readyProbe == nil && (restartPolicy == "" || restartPolicy == plugins.RestartNever) {
+		return plugins.Run(ctx, run, dnsMgr, subdomain)
+	}
+
+	getter, ok := dnsMgr.(plugins.ReadyGetter)
+	if !ok {
+		return errors.New("readiness probes and restarts are not supported")
+	}
+
+	return plugins.RunWithOptions(ctx, run, getter, subdomain, plugins.RunOptions{
+		Probe:   readyProbe,
+		Restart: restartPolicy,
+	})
+}
+
+// buildExecutable builds the plugin directory as a normal executable for --subprocess
+func buildExecutable() (string, error) {
+	fname, err := plugins.BuildExecutable(pluginFilename, plugins.BuildOptions{
+		Flags: strings.Fields(buildFlags),
+		Tags:  buildTags,
+	})
+	if err != nil {
+		errors.PrintUserFixableErrorInstruction(err)
+		return "", fmt.Errorf("error building executable: %w", err)
+	}
+
+	return fname, nil
+}
+
+// runWatch runs the executable from build and restarts it when anything in path changes. The IP is allocated once
+// and kept until Goblin exits, so the subdomain isn't released between restarts. The new executable is built before
+// the old process is stopped, so it keeps running if the build fails
+func runWatch(ctx context.Context, dnsMgr plugins.ReadyGetter, path, subdomain string, build func() (string, error)) error {
+	// signals are forwarded to the running process, so they are also received here to exit when it does instead
+	// of waiting for the next change
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	getIP := dnsMgr.GetIP
+	if readyProbe != nil {
+		getIP = dnsMgr.GetPendingIP
+	}
+
+	ip, err := getIP(ctx, subdomain)
+	if err != nil {
+		return fmt.Errorf("error getting IP: %w", err)
+	}
+
+	// the probe is checked after each start and DNS uses the fallback route while the process is stopped or
+	// starting, so it only uses the IP when the latest build is ready
+	var ready func(context.Context)
+	setPending := func() {}
+	if readyProbe != nil {
+		ready = func(ctx context.Context) {
+			plugins.ReportReady(ctx, dnsMgr, subdomain, ip, readyProbe, plugins.DefaultProbeInterval, slog.Default())
+		}
+		setPending = func() {
+			err := dnsMgr.SetPending(ctx, subdomain)
+			if err != nil && ctx.Err() == nil {
+				slog.Error("error setting IP pending", "subdomain", subdomain, "error", err)
+			}
+		}
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		err := watch.Poll(ctx, path, reloadPollInterval, func() {
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		})
+		if err != nil {
+			slog.Error("error watching for changes", "path", path, "error", err)
+		}
+	}()
+
+	var proc *watchedProcess
+	defer func() {
+		proc.stop()
+	}()
+
+	for {
+		fname, err := build()
+		if err != nil {
+			slog.Error("error building, waiting for changes", "subdomain", subdomain, "error", err)
+		} else {
+			run, err := plugins.Exec(fname, execArgs, plugins.ExecOptions{IPEnvVar: ipEnvVar, ForwardSignals: true})
+			if err != nil {
+				errors.PrintUserFixableErrorInstruction(err)
+				return fmt.Errorf("error loading executable: %w", err)
+			}
+
+			if proc != nil {
+				slog.Info("restarting process", "subdomain", subdomain)
+				setPending()
+				proc.stop()
+			}
+
+			slog.Info("starting process", "subdomain", subdomain, "executable", fname, "ip", ip)
+			proc = startWatchedProcess(ctx, run, ip, ready)
+		}
+
+		exiting := false
+	wait:
+		for {
+			select {
+			case <-changes:
+				if !exiting {
+					break wait
+				}
+			case err := <-proc.exited():
+				proc = nil
+				if exiting {
+					slog.Info("stopped process", "subdomain", subdomain)
+					return nil
+				}
+				slog.Warn("process exited, waiting for changes", "subdomain", subdomain, "error", err)
+				setPending()
+			case <-signals:
+				if proc == nil {
+					return nil
+				}
+				// the signal was forwarded to the process, so wait for it to exit
+				exiting = true
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// watchedProcess is a process started by runWatch
+type watchedProcess struct {
+	cancel context.CancelFunc
+	done   chan error
+}
+
+// startWatchedProcess runs the process in a goroutine. If ready is not nil, it is also run in a goroutine with
+// a context that is done when the process is stopped
+func startWatchedProcess(ctx context.Context, run plugins.RunFunc, ip string, ready func(context.Context)) *watchedProcess {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &watchedProcess{cancel: cancel, done: make(chan error, 1)}
+	go func() {
+		p.done <- run(ctx, ip)
+	}()
+	if ready != nil {
+		go ready(ctx)
+	}
+	return p
+}
+
+// exited receives the process's error when it exits. It is nil when there is no process, so it blocks forever
+func (p *watchedProcess) exited() <-chan error {
+	if p == nil {
+		return nil
+	}
+	return p.done
+}
+
+// stop cancels the process and waits for it to exit
+func (p *watchedProcess) stop() {
+	if p == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+}
+
+// SplitExecArgs removes the arguments after -- for the run command so they can be passed to the executable. The
+// CLI library parses arguments after -- as flags, so they are removed before it runs. Other commands get all of
+// their arguments
+func SplitExecArgs(args []string) []string {
+	i := slices.Index(args, "--")
+	if i < 0 || !slices.Contains(RunCmd.Names(), commandName(args[1:i])) {
+		return args
+	}
+
+	execArgs = args[i+1:]
+	return args[:i]
+}
+
+// commandName finds the sub-command in the arguments by skipping the root command's flags and their values
+func commandName(args []string) string {
+	for i := 0; i < len(args); i++ {
+		name, isFlag := strings.CutPrefix(args[i], "-")
+		if !isFlag {
+			return args[i]
+		}
+
+		name = strings.TrimPrefix(name, "-")
+		if !strings.Contains(name, "=") && rootFlagTakesValue(name) {
+			i++
+		}
+	}
+
+	return ""
+}
+
+// rootFlagTakesValue is true if the root command's flag is followed by a value, like --log-level debug
+func rootFlagTakesValue(name string) bool {
+	for _, f := range LoggingFlags {
+		if !slices.Contains(f.Names(), name) {
+			continue
+		}
+
+		_, isBool := f.(*cli.BoolFlag)
+		return !isBool
+	}
+
+	return false
+}
+
+// pluginSubdomain is the default subdomain for a plugin, which is its filename without .so
+func pluginSubdomain(fname string) string {
+	return strings.TrimSuffix(filepath.Base(fname), ".so")
+}
+
+func runPlugin(ctx context.Context, dnsMgr plugins.IPGetter, fname, subdomain string, env plugins.EnvConfig, timeout time.Duration) error {
 	if timeout != 0 {
 		timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
@@ -93,7 +570,7 @@ func runPlugin(ctx context.Context, dnsMgr plugins.IPGetter, fname, subdomain st
 	}
 
 	if subdomain == "" {
-		subdomain = strings.TrimSuffix(filepath.Base(fname), ".so")
+		subdomain = pluginSubdomain(fname)
 	}
 
 	if isDir {
@@ -110,7 +587,11 @@ func runPlugin(ctx context.Context, dnsMgr plugins.IPGetter, fname, subdomain st
 	if ipEnvVar != "" {
 		run, err = plugins.LoadMainWithIPEnvVar(fname, ipEnvVar)
 	} else {
-		run, err = plugins.Load(fname)
+		env.Subdomain, err = dns.NormalizeSubdomain(subdomain)
+		if err != nil {
+			return err
+		}
+		run, err = plugins.Load(fname, env)
 	}
 
 	if err != nil {
@@ -118,12 +599,12 @@ func runPlugin(ctx context.Context, dnsMgr plugins.IPGetter, fname, subdomain st
 		return fmt.Errorf("error loading plugin: %w", err)
 	}
 
-	log.Printf("starting plugin: %q", subdomain)
-	err = plugins.Run(ctx, run, dnsMgr, subdomain)
+	slog.Info("starting plugin", "subdomain", subdomain)
+	err = startRun(ctx, run, dnsMgr, subdomain)
 	if err != nil {
 		return fmt.Errorf("error running plugin: %w", err)
 	}
 
-	log.Printf("stopped plugin: %q", subdomain)
+	slog.Info("stopped plugin", "subdomain", subdomain)
 	return err
 }
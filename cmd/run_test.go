@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitExecArgs(t *testing.T) {
+	tests := []struct {
+		name             string
+		args             []string
+		expectedArgs     []string
+		expectedExecArgs []string
+	}{
+		{
+			"NoSeparator",
+			[]string{"goblin", "run", "-x", "server"},
+			[]string{"goblin", "run", "-x", "server"},
+			nil,
+		},
+		{
+			"Run",
+			[]string{"goblin", "run", "-x", "server", "--", "--addr={{.IP}}:8080", "-v"},
+			[]string{"goblin", "run", "-x", "server"},
+			[]string{"--addr={{.IP}}:8080", "-v"},
+		},
+		{
+			"RunWithRootFlags",
+			[]string{"goblin", "--log-level", "debug", "--log-format=json", "run", "-x", "server", "--", "-v"},
+			[]string{"goblin", "--log-level", "debug", "--log-format=json", "run", "-x", "server"},
+			[]string{"-v"},
+		},
+		{
+			"RootFlagValueIsNotCommand",
+			[]string{"goblin", "--log-level", "run", "register", "-d", "app", "--", "x"},
+			[]string{"goblin", "--log-level", "run", "register", "-d", "app", "--", "x"},
+			nil,
+		},
+		{
+			"OtherCommand",
+			[]string{"goblin", "register", "-d", "run", "--", "--address", "x"},
+			[]string{"goblin", "register", "-d", "run", "--", "--address", "x"},
+			nil,
+		},
+		{
+			"SeparatorInExecArgs",
+			[]string{"goblin", "run", "-x", "server", "--", "a", "--", "b"},
+			[]string{"goblin", "run", "-x", "server"},
+			[]string{"a", "--", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execArgs = nil
+			t.Cleanup(func() { execArgs = nil })
+
+			args := SplitExecArgs(tt.args)
+			if !slices.Equal(args, tt.expectedArgs) {
+				t.Errorf("expected args %q, got %q", tt.expectedArgs, args)
+			}
+			if !slices.Equal(execArgs, tt.expectedExecArgs) {
+				t.Errorf("expected exec args %q, got %q", tt.expectedExecArgs, execArgs)
+			}
+		})
+	}
+}
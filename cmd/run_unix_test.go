@@ -0,0 +1,122 @@
+//go:build unix
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// helperProcessEnvVar makes the test binary act as a child process that runs until it is stopped
+const helperProcessEnvVar = "GOBLIN_TEST_HELPER_PROCESS"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperProcessEnvVar) == "1" {
+		time.Sleep(time.Hour)
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// recordingReadyGetter records the calls that change whether DNS uses the IP
+type recordingReadyGetter struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (g *recordingReadyGetter) record(call string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls = append(g.calls, call)
+}
+
+func (g *recordingReadyGetter) recorded() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return slices.Clone(g.calls)
+}
+
+func (g *recordingReadyGetter) GetIP(context.Context, string) (string, error) {
+	g.record("GetIP")
+	return "127.0.0.1", nil
+}
+
+func (g *recordingReadyGetter) GetPendingIP(context.Context, string) (string, error) {
+	g.record("GetPendingIP")
+	return "127.0.0.1", nil
+}
+
+func (g *recordingReadyGetter) SetReady(context.Context, string) error {
+	g.record("SetReady")
+	return nil
+}
+
+func (g *recordingReadyGetter) SetPending(context.Context, string) error {
+	g.record("SetPending")
+	return nil
+}
+
+// waitForCalls waits until the getter has the expected calls
+func waitForCalls(t *testing.T, getter *recordingReadyGetter, expected []string) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !slices.Equal(getter.recorded(), expected) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected calls %v, got %v", expected, getter.recorded())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunWatchSetsPendingOnRestart(t *testing.T) {
+	t.Setenv(helperProcessEnvVar, "1")
+
+	originalProbe := readyProbe
+	readyProbe = func(context.Context, string) error { return nil }
+	t.Cleanup(func() { readyProbe = originalProbe })
+
+	source := filepath.Join(t.TempDir(), "main.go")
+	err := os.WriteFile(source, []byte("package main\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getter := &recordingReadyGetter{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- runWatch(ctx, getter, source, "app", func() (string, error) {
+			return os.Args[0], nil
+		})
+	}()
+
+	// stop the process even if the test fails so it doesn't keep running
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Error("timed out waiting for runWatch to return")
+		}
+	})
+
+	waitForCalls(t, getter, []string{"GetPendingIP", "SetReady"})
+
+	err = os.WriteFile(source, []byte("package main\n\nfunc main() {}\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// DNS uses the fallback route until the rebuilt process is ready
+	waitForCalls(t, getter, []string{"GetPendingIP", "SetReady", "SetPending", "SetReady"})
+}
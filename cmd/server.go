@@ -2,14 +2,14 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
 	"log/slog"
 	"net"
-	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/calvinmclean/goblin/auth"
+	"github.com/calvinmclean/goblin/containers"
 	"github.com/calvinmclean/goblin/dns"
 	"github.com/calvinmclean/goblin/errors"
 	"github.com/calvinmclean/goblin/server"
@@ -28,6 +28,11 @@ var (
 	portEnvVar = cli.EnvVar("GOBLIN_PORT")
 
 	topLevelDomain, fallbackConfig, serverPort, dnsPort string
+	grpcPort, grpcSocket                                string
+	fallbackReloadInterval                              time.Duration
+	queryLogFile                                        string
+	queryLogSize, queryLogFileMB                        int64
+	requireAuth, serveUnix, serveTCP                    bool
 	ServerCmd                                           = &cli.Command{
 		Name:        "server",
 		Description: "run server",
@@ -41,6 +46,7 @@ var (
 				Destination: &topLevelDomain,
 			},
 			portFlag,
+			socketFlag,
 			&cli.StringFlag{
 				Name:        "dns-port",
 				Aliases:     []string{"s"},
@@ -53,51 +59,176 @@ var (
 				Aliases:   []string{"r"},
 				TakesFile: true,
 				Validator: func(v string) error {
-					if filepath.Ext(v) != ".json" {
-						return errors.New("fallback-routes must be JSON file")
+					switch filepath.Ext(v) {
+					case ".json", ".yaml", ".yml", ".toml":
+						return nil
 					}
-					return nil
+					return errors.New("fallback-routes must be a JSON, YAML, or TOML file")
 				},
-				Usage: `path to a JSON file holding fallback route config in this format:
+				Usage: `path to a JSON, YAML, or TOML file holding fallback route config in this format:
 {
   "subdomain": "remote-server.com"
+}
+or with more options:
+{
+  "routes": {
+    "subdomain": {"target": "remote-server.com", "ttl": "30s"}
+  },
+  "reservations": {"subdomain": "10.0.0.1"}
 }`,
 				Destination: &fallbackConfig,
 			},
+			serverDockerSocketFlag,
+			runtimeFlag,
+			&cli.IntFlag{
+				Name:        "query-log-size",
+				Value:       dns.DefaultQueryLogSize,
+				Usage:       "number of recent DNS queries to keep in memory",
+				Destination: &queryLogSize,
+			},
+			&cli.StringFlag{
+				Name:        "query-log-file",
+				TakesFile:   true,
+				Usage:       "path to a JSONL file where all DNS queries are appended",
+				Destination: &queryLogFile,
+			},
+			&cli.IntFlag{
+				Name:        "query-log-file-mb",
+				Value:       dns.DefaultQueryLogFileBytes / 1024 / 1024,
+				Usage:       "size in MB of the query-log-file before it is rotated",
+				Destination: &queryLogFileMB,
+			},
+			&cli.DurationFlag{
+				Name:        "fallback-reload-interval",
+				Value:       2 * time.Second,
+				Usage:       "how often to check the fallback-routes file for changes. Use 0 to disable reloading",
+				Destination: &fallbackReloadInterval,
+			},
+			&cli.StringFlag{
+				Name:        "grpc-port",
+				Usage:       "port to serve the gRPC API on. The gRPC API is disabled if this and --grpc-socket are not set. Requires building with -tags grpc",
+				Destination: &grpcPort,
+				Sources:     cli.ValueSourceChain{Chain: []cli.ValueSource{cli.EnvVar("GOBLIN_GRPC_PORT")}},
+			},
+			&cli.StringFlag{
+				Name:        "grpc-socket",
+				Usage:       "path to a Unix socket to serve the gRPC API on. Only your user can access it",
+				TakesFile:   true,
+				Destination: &grpcSocket,
+				Sources:     cli.ValueSourceChain{Chain: []cli.ValueSource{cli.EnvVar("GOBLIN_GRPC_SOCKET")}},
+			},
+			&cli.BoolFlag{
+				Name:        "unix",
+				Usage:       "serve the API on a Unix socket that only your user can access. The default socket is in your runtime directory, or set the path with --socket",
+				Destination: &serveUnix,
+			},
+			&cli.BoolFlag{
+				Name:        "tcp",
+				Value:       true,
+				Usage:       "serve the API and dashboard on --port. Use --tcp=false to only serve the API on the Unix socket",
+				Destination: &serveTCP,
+			},
+			&cli.BoolFlag{
+				Name:        "auth",
+				Usage:       "require a token to use the API. The token is generated and stored in a file only readable by your user, where it is read by the CLI",
+				Destination: &requireAuth,
+				Sources:     cli.ValueSourceChain{Chain: []cli.ValueSource{cli.EnvVar("GOBLIN_AUTH")}},
+			},
 		},
 	}
 )
 
 func runServer(ctx context.Context, c *cli.Command) error {
-	slog.SetLogLoggerLevel(slog.LevelDebug)
+	if !serveTCP && !serveUnix && socketPath == "" {
+		return errors.New("--tcp=false requires serving on a Unix socket with --unix or --socket")
+	}
 
-	var fallbackRoutes dns.FallbackRoutes
-	if fallbackConfig != "" {
-		data, err := os.ReadFile(fallbackConfig)
+	if grpcPort != "" || grpcSocket != "" {
+		err := server.CheckGRPC()
 		if err != nil {
-			return fmt.Errorf("error opening fallback routes config: %w", err)
+			errors.PrintUserFixableErrorInstruction(err)
+			return err
 		}
+	}
 
-		err = json.Unmarshal(data, &fallbackRoutes)
+	var fallbacks dns.FallbackConfig
+	if fallbackConfig != "" {
+		var err error
+		fallbacks, err = dns.LoadFallbackConfig(fallbackConfig)
 		if err != nil {
-			return fmt.Errorf("error parsing fallback routes config: %w", err)
+			return fmt.Errorf("error loading fallback routes config: %w", err)
 		}
 	}
 
 	dnsMgr, err := dns.New(dns.Config{
-		Domain:         topLevelDomain,
-		Address:        net.JoinHostPort(defaultAddr, dnsPort),
-		FallbackRoutes: fallbackRoutes,
+		Domain:            topLevelDomain,
+		Address:           net.JoinHostPort(defaultAddr, dnsPort),
+		FallbackRoutes:    fallbacks.Routes,
+		Reservations:      fallbacks.Reservations,
+		QueryLogSize:      int(queryLogSize),
+		QueryLogFile:      queryLogFile,
+		QueryLogFileBytes: queryLogFileMB * 1024 * 1024,
+		Logger:            slog.Default(),
 	})
 	if err != nil {
 		errors.PrintUserFixableErrorInstruction(err)
 		return fmt.Errorf("error creating DNS Manager: %w", err)
 	}
 
-	server := server.New(dnsMgr, net.JoinHostPort(defaultAddr, serverPort))
+	if fallbackConfig != "" && fallbackReloadInterval > 0 {
+		go func() {
+			err := dnsMgr.WatchFallbackConfig(ctx, fallbackConfig, fallbackReloadInterval)
+			if err != nil {
+				slog.Error("error watching fallback routes config", "error", err)
+			}
+		}()
+	}
+
+	var addr string
+	if serveTCP {
+		addr = net.JoinHostPort(defaultAddr, serverPort)
+	}
+
+	// the container runtime might be started after the server, so use the default Docker socket if it isn't found
+	runtime, err := newRuntime(ctx)
+	if err != nil {
+		slog.Warn("container runtime not found, using the default Docker socket", "error", err)
+		runtime = containers.NewDocker(containers.DefaultDockerSocket)
+	}
+
+	server := server.New(dnsMgr, addr, runtime, slog.Default())
+	if grpcPort != "" {
+		server = server.WithGRPC(net.JoinHostPort(defaultAddr, grpcPort))
+	}
+	if grpcSocket != "" {
+		server = server.WithGRPCSocket(grpcSocket)
+	}
+	if serveUnix || socketPath != "" {
+		if socketPath == "" {
+			socketPath = dns.DefaultSocketPath()
+		}
+		server = server.WithUnixSocket(socketPath)
+	}
+
+	if requireAuth {
+		tokenFile, err := auth.TokenFile()
+		if err != nil {
+			return err
+		}
+
+		token, err := auth.LoadOrCreateToken(tokenFile)
+		if err != nil {
+			errors.PrintUserFixableErrorInstruction(err)
+			return fmt.Errorf("error loading API token: %w", err)
+		}
+
+		slog.Info("API requires authentication", "token_file", tokenFile)
+		server = server.WithToken(token)
+	}
+
 	err = server.Run(ctx)
 	if err != nil {
-		log.Fatalf("error running GRPC server: %v", err)
+		return fmt.Errorf("error running server: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/urfave/cli/v3"
+)
+
+var UnregisterCmd = &cli.Command{
+	Name:        "unregister",
+	Description: "remove a fallback route from the server",
+	Action:      runUnregister,
+	Flags: []cli.Flag{
+		portFlag,
+		socketFlag,
+		&cli.StringFlag{
+			Name:        "subdomain",
+			Aliases:     []string{"d"},
+			Usage:       "subdomain name",
+			Destination: &subdomain,
+			Required:    true,
+		},
+	},
+}
+
+func runUnregister(ctx context.Context, c *cli.Command) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error creating client: %w", err)
+	}
+
+	err = client.RemoveFallback(ctx, subdomain)
+	if err != nil {
+		return fmt.Errorf("error removing fallback: %w", err)
+	}
+	slog.Info("removed fallback", "subdomain", subdomain)
+
+	return nil
+}
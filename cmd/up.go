@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/calvinmclean/goblin/supervisor"
+
+	"github.com/urfave/cli/v3"
+)
+
+var (
+	manifestFilename string
+	UpCmd            = &cli.Command{
+		Name:        "up",
+		Description: "run all services from a manifest file and restart them if they crash",
+		Action:      runUp,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "file",
+				Aliases:     []string{"f"},
+				Value:       "goblin.yaml",
+				Usage:       "manifest file with the services to run. JSON, YAML, and TOML are supported",
+				TakesFile:   true,
+				Destination: &manifestFilename,
+			},
+			portFlag,
+			socketFlag,
+		},
+	}
+)
+
+func runUp(ctx context.Context, c *cli.Command) error {
+	manifest, err := supervisor.LoadManifest(manifestFilename)
+	if err != nil {
+		return fmt.Errorf("error loading manifest: %w", err)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error creating client: %w", err)
+	}
+
+	env, err := pluginEnv(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return supervisor.New(manifest, client, env, slog.Default()).Run(ctx)
+}
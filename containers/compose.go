@@ -0,0 +1,68 @@
+package containers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+)
+
+var errNoComposeContainers = errors.New("no running containers found for compose project")
+
+// ComposeService is a running container for a service in a Docker Compose project
+type ComposeService struct {
+	Project string
+	Service string
+	Container
+}
+
+// Subdomain is the service name, optionally followed by the project name like service.project
+func (s ComposeService) Subdomain(includeProject bool) string {
+	if includeProject {
+		return s.Service + "." + s.Project
+	}
+	return s.Service
+}
+
+// ComposeServices gets a running container for each service in the Compose project. If a service is scaled
+// to multiple containers, the first by name is used
+func (d Docker) ComposeServices(ctx context.Context, project string) ([]ComposeService, error) {
+	return d.composeServices(ctx, project, composeProjectLabel, composeServiceLabel)
+}
+
+// composeServices gets the containers for a project using the labels set by the compose tool
+func (d Docker) composeServices(ctx context.Context, project, projectLabel, serviceLabel string) ([]ComposeService, error) {
+	running, err := d.Containers(ctx, projectLabel+"="+project)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(running, func(a, b Container) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	var result []ComposeService
+	seen := map[string]bool{}
+	for _, c := range running {
+		service := c.Labels[serviceLabel]
+		if service == "" || seen[service] {
+			continue
+		}
+		seen[service] = true
+
+		result = append(result, ComposeService{project, service, c})
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w %q", errNoComposeContainers, project)
+	}
+
+	return result, nil
+}
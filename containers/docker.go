@@ -0,0 +1,361 @@
+package containers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+const DefaultDockerSocket = "/var/run/docker.sock"
+
+var (
+	errNoIP         = errors.New("no IP address found for container")
+	errNotConnected = errors.New("container is not connected to network")
+)
+
+// Docker is a Runtime that uses the Docker Engine API over a unix socket to get details about containers
+type Docker struct {
+	client  *http.Client
+	network string
+}
+
+// Container has the details used to route to a container
+type Container struct {
+	ID      string
+	Name    string
+	Labels  map[string]string
+	Running bool
+	// IP is the address on the preferred network if the container is connected to it. Otherwise, it is
+	// from the first network sorted by name so the choice is consistent
+	IP string
+	// Networks has the container's IP on each network it is connected to
+	Networks map[string]string
+	// Ports are the container's ports that are published on the host
+	Ports []Port
+}
+
+// Port is a container port that is published on the host
+type Port struct {
+	Port     int
+	Protocol string
+	HostIP   string
+	HostPort int
+}
+
+// Event is a container lifecycle or network event from the Docker events API
+type Event struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+}
+
+// Stopped is true if the event is for a container that stopped. Podman uses died instead of die
+func (e Event) Stopped() bool {
+	return e.Type == "container" && (e.Action == "die" || e.Action == "died")
+}
+
+// ContainerID is the ID of the container that the event is about. Network events have the container ID
+// in an attribute since the actor is the network
+func (e Event) ContainerID() string {
+	if e.Type == "network" {
+		return e.Actor.Attributes["container"]
+	}
+	return e.Actor.ID
+}
+
+type networks map[string]struct {
+	IPAddress string `json:"IPAddress"`
+}
+
+// ips gets the container's IP on each network that it has one for
+func (n networks) ips() map[string]string {
+	result := map[string]string{}
+	for name, network := range n {
+		if network.IPAddress != "" {
+			result[name] = network.IPAddress
+		}
+	}
+	return result
+}
+
+// ip chooses the IP from the preferred network, or the first network by name
+func (d Docker) ip(ips map[string]string) string {
+	ip, ok := ips[d.network]
+	if ok {
+		return ip
+	}
+
+	names := slices.Sorted(maps.Keys(ips))
+	if len(names) == 0 {
+		return ""
+	}
+	return ips[names[0]]
+}
+
+func NewDocker(socket string) Docker {
+	return Docker{
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+// Name is the name of the container runtime
+func (d Docker) Name() string {
+	return RuntimeDocker
+}
+
+// WithNetwork prefers IPs from this network for containers that are connected to more than one
+func (d Docker) WithNetwork(network string) Runtime {
+	d.network = network
+	return d
+}
+
+// ContainerIP gets the IP address of a running container. If a network is set with WithNetwork, the
+// container must be connected to it
+func (d Docker) ContainerIP(ctx context.Context, containerName string) (string, error) {
+	c, err := d.RunningContainer(ctx, containerName)
+	if err != nil {
+		return "", err
+	}
+
+	return c.IP, nil
+}
+
+// RunningContainer inspects a container and checks that it has an IP that can be routed to. If a network
+// is set with WithNetwork, the container must be connected to it
+func (d Docker) RunningContainer(ctx context.Context, containerName string) (Container, error) {
+	c, err := d.Container(ctx, containerName)
+	if err != nil {
+		return Container{}, err
+	}
+
+	if d.network != "" && c.Networks[d.network] == "" {
+		return Container{}, fmt.Errorf("%w %q", errNotConnected, d.network)
+	}
+
+	if c.IP == "" {
+		return Container{}, errNoIP
+	}
+
+	return c, nil
+}
+
+// Container inspects a container by name or ID
+func (d Docker) Container(ctx context.Context, nameOrID string) (Container, error) {
+	var containerData struct {
+		ID     string `json:"Id"`
+		Name   string `json:"Name"`
+		Config struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"Config"`
+		State struct {
+			Running bool `json:"Running"`
+		} `json:"State"`
+		NetworkSettings struct {
+			Networks networks `json:"Networks"`
+			Ports    map[string][]struct {
+				HostIP   string `json:"HostIp"`
+				HostPort string `json:"HostPort"`
+			} `json:"Ports"`
+		} `json:"NetworkSettings"`
+	}
+	err := d.get(ctx, "/containers/"+url.PathEscape(nameOrID)+"/json", &containerData)
+	if err != nil {
+		return Container{}, err
+	}
+
+	// ports are keyed by port and protocol like 80/tcp, and bindings are empty if the port isn't published
+	var ports []Port
+	for key, bindings := range containerData.NetworkSettings.Ports {
+		port, protocol, _ := strings.Cut(key, "/")
+		containerPort, err := strconv.Atoi(port)
+		if err != nil {
+			continue
+		}
+
+		for _, b := range bindings {
+			hostPort, err := strconv.Atoi(b.HostPort)
+			if err != nil {
+				continue
+			}
+			ports = append(ports, Port{containerPort, protocol, b.HostIP, hostPort})
+		}
+	}
+
+	ips := containerData.NetworkSettings.Networks.ips()
+	return Container{
+		ID:       containerData.ID,
+		Name:     strings.TrimPrefix(containerData.Name, "/"),
+		Labels:   containerData.Config.Labels,
+		Running:  containerData.State.Running,
+		IP:       d.ip(ips),
+		Networks: ips,
+		Ports:    ports,
+	}, nil
+}
+
+// Containers lists the running containers. Labels can be used to only list containers that have
+// the label, using "key" or "key=value"
+func (d Docker) Containers(ctx context.Context, labels ...string) ([]Container, error) {
+	var containerData []struct {
+		ID     string            `json:"Id"`
+		Names  []string          `json:"Names"`
+		Labels map[string]string `json:"Labels"`
+		Ports  []struct {
+			IP          string `json:"IP"`
+			PrivatePort int    `json:"PrivatePort"`
+			PublicPort  int    `json:"PublicPort"`
+			Type        string `json:"Type"`
+		} `json:"Ports"`
+		NetworkSettings struct {
+			Networks networks `json:"Networks"`
+		} `json:"NetworkSettings"`
+	}
+	path := "/containers/json"
+	if len(labels) > 0 {
+		filters, err := json.Marshal(map[string][]string{"label": labels})
+		if err != nil {
+			return nil, fmt.Errorf("error encoding filters: %w", err)
+		}
+		path += "?" + url.Values{"filters": {string(filters)}}.Encode()
+	}
+
+	err := d.get(ctx, path, &containerData)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Container, 0, len(containerData))
+	for _, c := range containerData {
+		var name string
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+
+		var ports []Port
+		for _, p := range c.Ports {
+			if p.PublicPort != 0 {
+				ports = append(ports, Port{p.PrivatePort, p.Type, p.IP, p.PublicPort})
+			}
+		}
+
+		ips := c.NetworkSettings.Networks.ips()
+		result = append(result, Container{
+			ID:       c.ID,
+			Name:     name,
+			Labels:   c.Labels,
+			Running:  true,
+			IP:       d.ip(ips),
+			Networks: ips,
+			Ports:    ports,
+		})
+	}
+
+	return result, nil
+}
+
+// Events streams container start and die events and network connect and disconnect events. The
+// subscription is started before returning, and the channel is closed when the context is done or
+// the connection to Docker is lost
+func (d Docker) Events(ctx context.Context) (<-chan Event, error) {
+	return d.events(ctx, "start", "die", "connect", "disconnect")
+}
+
+// events streams container and network events with the actions
+func (d Docker) events(ctx context.Context, actions ...string) (<-chan Event, error) {
+	filters, err := json.Marshal(map[string][]string{
+		"type":  {"container", "network"},
+		"event": actions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding filters: %w", err)
+	}
+
+	resp, err := d.request(ctx, "/events?"+url.Values{"filters": {string(filters)}}.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var e Event
+			err := decoder.Decode(&e)
+			if err != nil {
+				return
+			}
+
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// get makes a GET request to the API and parses the JSON response
+func (d Docker) get(ctx context.Context, path string, out any) error {
+	resp, err := d.request(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return nil
+}
+
+// request makes a GET request to the API and returns the response if it has an OK status
+func (d Docker) request(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost"+path, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading response body: %w", err)
+		}
+		return nil, fmt.Errorf("unexpected response status %d: %s", resp.StatusCode, body)
+	}
+
+	return resp, nil
+}
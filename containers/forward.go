@@ -0,0 +1,157 @@
+package containers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+const reachableTimeout = time.Second
+
+// Reachable checks if the container's IP can be reached from the host by connecting to one of its published
+// ports. Docker Desktop runs containers in a VM, so their IPs are only reachable inside of it. Containers
+// without published TCP ports are assumed to be reachable since there is nothing to forward
+func (c Container) Reachable(ctx context.Context) bool {
+	ports := c.tcpPorts()
+	if len(ports) == 0 {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, reachableTimeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(c.IP, strconv.Itoa(ports[0].Port)))
+	if err != nil {
+		// the IP is reachable if the container refused the connection because nothing is listening yet
+		return errors.Is(err, syscall.ECONNREFUSED)
+	}
+	conn.Close()
+
+	return true
+}
+
+// tcpPorts gets the published TCP ports with one host binding for each container port
+func (c Container) tcpPorts() []Port {
+	var result []Port
+	seen := map[int]bool{}
+	for _, p := range c.Ports {
+		if p.Protocol != "tcp" || seen[p.Port] {
+			continue
+		}
+		seen[p.Port] = true
+		result = append(result, p)
+	}
+	return result
+}
+
+// hostAddr is the address to reach the published port on the host
+func (p Port) hostAddr() string {
+	host := p.HostIP
+	if host == "" || net.ParseIP(host).IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(p.HostPort))
+}
+
+// ForwardPorts listens on the container's ports at the IP and proxies connections to the ports published on the
+// host, so the container is reached on the same ports as it would be at its own IP. Only TCP ports are forwarded.
+// It returns after starting the listeners, and they are closed when the context is done
+func ForwardPorts(ctx context.Context, ip string, c Container, logger *slog.Logger) error {
+	_, err := forwardPorts(ctx, ip, c, logger)
+	return err
+}
+
+// forwardPorts is ForwardPorts, and the returned channel is closed after the listeners are closed so the ports
+// can be listened on again
+func forwardPorts(ctx context.Context, ip string, c Container, logger *slog.Logger) (<-chan struct{}, error) {
+	var listeners []net.Listener
+	for _, p := range c.tcpPorts() {
+		var lc net.ListenConfig
+		ln, err := lc.Listen(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(p.Port)))
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, ln)
+
+		logger.Info("forwarding port", "container", c.Name, "addr", ln.Addr().String(), "target", p.hostAddr())
+		go forward(ctx, ln, p.hostAddr(), logger)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+
+		<-ctx.Done()
+		for _, ln := range listeners {
+			ln.Close()
+		}
+	}()
+
+	return closed, nil
+}
+
+// forward accepts connections until the listener is closed and proxies them to the target
+func forward(ctx context.Context, ln net.Listener, target string, logger *slog.Logger) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Error("error accepting connection", "addr", ln.Addr().String(), "error", err)
+			}
+			return
+		}
+
+		go proxy(ctx, conn, target, logger)
+	}
+}
+
+func proxy(ctx context.Context, conn net.Conn, target string, logger *slog.Logger) {
+	defer conn.Close()
+
+	var d net.Dialer
+	targetConn, err := d.DialContext(ctx, "tcp", target)
+	if err != nil {
+		logger.Error("error connecting to published port", "target", target, "error", err)
+		return
+	}
+	defer targetConn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// close the connections when forwarding stops so the copies are interrupted
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+		targetConn.Close()
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		copyAndCloseWrite(targetConn, conn)
+		close(done)
+	}()
+	copyAndCloseWrite(conn, targetConn)
+	<-done
+}
+
+// copyAndCloseWrite copies until the source is done and then closes the writing side of the destination so
+// the other end knows there is nothing left to read
+func copyAndCloseWrite(dst, src net.Conn) {
+	_, _ = io.Copy(dst, src)
+
+	tcpConn, ok := dst.(*net.TCPConn)
+	if ok {
+		_ = tcpConn.CloseWrite()
+	}
+}
@@ -0,0 +1,80 @@
+package containers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+const (
+	podmanComposeProjectLabel = "io.podman.compose.project"
+	podmanComposeServiceLabel = "io.podman.compose.service"
+)
+
+// Podman is a Runtime that uses Podman's Docker-compatible API
+type Podman struct {
+	Docker
+}
+
+// NewPodman creates a Podman runtime. If the socket is empty, the first Podman socket that exists is used
+func NewPodman(socket string) Podman {
+	if socket == "" {
+		socket = defaultPodmanSocket()
+	}
+	return Podman{NewDocker(socket)}
+}
+
+// Name is the name of the container runtime
+func (p Podman) Name() string {
+	return RuntimePodman
+}
+
+// WithNetwork prefers IPs from this network for containers that are connected to more than one
+func (p Podman) WithNetwork(network string) Runtime {
+	p.network = network
+	return p
+}
+
+// Events streams container start and stop events and network connect and disconnect events. Depending on
+// the version, Podman reports stopped containers with died instead of die
+func (p Podman) Events(ctx context.Context) (<-chan Event, error) {
+	return p.events(ctx, "start", "die", "died", "connect", "disconnect")
+}
+
+// ComposeServices gets a running container for each service in a project started by podman-compose or by
+// docker compose using the Podman socket
+func (p Podman) ComposeServices(ctx context.Context, project string) ([]ComposeService, error) {
+	services, err := p.composeServices(ctx, project, podmanComposeProjectLabel, podmanComposeServiceLabel)
+	if err == nil {
+		return services, nil
+	}
+
+	return p.composeServices(ctx, project, composeProjectLabel, composeServiceLabel)
+}
+
+// podmanSockets are the default sockets for rootless Podman, rootful Podman, and Podman machine on macOS
+func podmanSockets() []string {
+	var sockets []string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		sockets = append(sockets, filepath.Join(runtimeDir, "podman", "podman.sock"))
+	}
+
+	sockets = append(sockets, "/run/podman/podman.sock")
+
+	if home, err := os.UserHomeDir(); err == nil {
+		sockets = append(sockets, filepath.Join(home, ".local", "share", "containers", "podman", "machine", "podman.sock"))
+	}
+
+	return sockets
+}
+
+// defaultPodmanSocket is the first Podman socket that exists, or the rootless socket
+func defaultPodmanSocket() string {
+	sockets := podmanSockets()
+	for _, socket := range sockets {
+		if _, err := os.Stat(socket); err == nil {
+			return socket
+		}
+	}
+	return sockets[0]
+}
@@ -0,0 +1,71 @@
+package containers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+var errNoPublishedPorts = errors.New("container IP is not reachable from the host and it has no published TCP ports to forward")
+
+// Registrar allocates IPs and manages fallback routes. It is implemented by dns.Client
+type Registrar interface {
+	GetIP(ctx context.Context, subdomain string) (string, error)
+	RegisterFallback(ctx context.Context, subdomain, address string, expiresIn time.Duration) error
+	RemoveFallback(ctx context.Context, subdomain string) error
+}
+
+// RegisterOptions configures how Register routes to a container
+type RegisterOptions struct {
+	// ExpiresIn removes a fallback route after the duration. Forwarded routes last until the context is done
+	ExpiresIn time.Duration
+	// Forward always forwards the published ports instead of checking if the container's IP is reachable
+	Forward bool
+	// Logger defaults to slog.Default()
+	Logger *slog.Logger
+}
+
+// Register routes the subdomain to the container. If the container's IP is not reachable from the host, an IP
+// is allocated for the subdomain and the container's published ports are forwarded from it instead of
+// registering a fallback route. The IP is released and forwarding stops when the context is done, so forwarded
+// reports whether the context needs to be kept open for the route to work
+func Register(ctx context.Context, r Registrar, c Container, subdomain string, opts RegisterOptions) (forwarded bool, err error) {
+	if !opts.Forward && c.Reachable(ctx) {
+		return false, r.RegisterFallback(ctx, subdomain, c.IP, opts.ExpiresIn)
+	}
+
+	ip, err := allocate(ctx, r, c, subdomain)
+	if err != nil {
+		return false, err
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	err = ForwardPorts(ctx, ip, c, logger)
+	if err != nil {
+		return false, fmt.Errorf("error forwarding ports: %w", err)
+	}
+
+	return true, nil
+}
+
+// allocate gets an IP for the subdomain to forward the container's published ports from. The IP is released when
+// the context is done
+func allocate(ctx context.Context, r Registrar, c Container, subdomain string) (string, error) {
+	if len(c.tcpPorts()) == 0 {
+		return "", errNoPublishedPorts
+	}
+
+	ip, err := r.GetIP(ctx, subdomain)
+	if err != nil {
+		return "", fmt.Errorf("error getting IP: %w", err)
+	}
+
+	return ip, nil
+}
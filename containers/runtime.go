@@ -0,0 +1,127 @@
+package containers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+
+	runtimeNotFoundInstructions = `Start Docker or Podman, or set the path to its socket with DOCKER_SOCK.
+For Podman, the API socket can be started with:
+  systemctl --user enable --now podman.socket`
+)
+
+var (
+	errRuntimeNotFound = errors.New("no Docker or Podman socket found")
+	errUnknownRuntime  = errors.New("unknown container runtime")
+)
+
+// Runtime gets details and events for containers from a container engine. Docker and Podman are supported
+type Runtime interface {
+	// Name is the name of the container runtime, like docker
+	Name() string
+	// WithNetwork prefers IPs from this network for containers that are connected to more than one
+	WithNetwork(network string) Runtime
+
+	Container(ctx context.Context, nameOrID string) (Container, error)
+	RunningContainer(ctx context.Context, containerName string) (Container, error)
+	ContainerIP(ctx context.Context, containerName string) (string, error)
+	Containers(ctx context.Context, labels ...string) ([]Container, error)
+	Events(ctx context.Context) (<-chan Event, error)
+	ComposeServices(ctx context.Context, project string) ([]ComposeService, error)
+}
+
+// NewRuntime creates the named Runtime. If the socket is empty, the runtime's default socket is used
+func NewRuntime(name, socket string) (Runtime, error) {
+	switch name {
+	case RuntimeDocker:
+		if socket == "" {
+			socket = DefaultDockerSocket
+		}
+		return NewDocker(socket), nil
+	case RuntimePodman:
+		return NewPodman(socket), nil
+	}
+
+	return nil, fmt.Errorf("%w %q", errUnknownRuntime, name)
+}
+
+// DetectRuntime finds the socket of a running container engine and uses its version to choose the Runtime.
+// If the socket is set, only the Runtime is detected. Otherwise, the unix socket from DOCKER_HOST and then
+// the default Docker and Podman sockets are checked
+func DetectRuntime(ctx context.Context, socket string) (Runtime, error) {
+	if socket == "" {
+		socket = findSocket()
+	}
+	if socket == "" {
+		return nil, errors.NewUserFixableError(errRuntimeNotFound, runtimeNotFoundInstructions)
+	}
+
+	docker := NewDocker(socket)
+	isPodman, err := docker.isPodman(ctx)
+	if err != nil {
+		return nil, errors.NewUserFixableError(
+			fmt.Errorf("error getting container runtime version from %q: %w", socket, err),
+			runtimeNotFoundInstructions,
+		)
+	}
+
+	if isPodman {
+		return Podman{docker}, nil
+	}
+	return docker, nil
+}
+
+// findSocket gets the first socket that exists
+func findSocket() string {
+	var sockets []string
+	if host, ok := strings.CutPrefix(os.Getenv("DOCKER_HOST"), "unix://"); ok {
+		sockets = append(sockets, host)
+	}
+
+	sockets = append(sockets, DefaultDockerSocket)
+
+	// Docker Desktop's socket is in the user's home directory when it isn't linked to the default
+	if home, err := os.UserHomeDir(); err == nil {
+		sockets = append(sockets, filepath.Join(home, ".docker", "run", "docker.sock"))
+	}
+
+	sockets = append(sockets, podmanSockets()...)
+
+	for _, socket := range sockets {
+		if _, err := os.Stat(socket); err == nil {
+			return socket
+		}
+	}
+	return ""
+}
+
+// isPodman checks the version components since Podman's Docker-compatible API is also used at the Docker
+// socket path by tools like podman-mac-helper
+func (d Docker) isPodman(ctx context.Context) (bool, error) {
+	var version struct {
+		Components []struct {
+			Name string `json:"Name"`
+		} `json:"Components"`
+	}
+	err := d.get(ctx, "/version", &version)
+	if err != nil {
+		return false, err
+	}
+
+	for _, c := range version.Components {
+		if strings.Contains(strings.ToLower(c.Name), RuntimePodman) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,254 @@
+package containers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+// SubdomainLabel is the container label that chooses the subdomain to register a container with
+const SubdomainLabel = "goblin.subdomain"
+
+var errEventStreamClosed = errors.New("container event stream closed")
+
+// Watcher uses container events to route to containers while they are running
+type Watcher struct {
+	runtime   Runtime
+	registrar Registrar
+	all       bool
+	forward   bool
+	logger    *slog.Logger
+
+	// routes are the routes registered by the Watcher by container ID. It is only used by Run's goroutine
+	routes map[string]route
+}
+
+type route struct {
+	subdomain, ip string
+	// forwarded is set if the container's ports are forwarded from an allocated IP instead of using a fallback route
+	forwarded *forwardedRoute
+}
+
+// forwardedRoute keeps an IP allocated for a container while forwarding can be restarted when its IP changes
+type forwardedRoute struct {
+	ip string
+	// ctx keeps the IP allocated until release is called
+	ctx     context.Context
+	release context.CancelFunc
+	// stop stops forwarding without releasing the IP, and stopped is closed when the ports are no longer used
+	stop    context.CancelFunc
+	stopped <-chan struct{}
+}
+
+// NewWatcher creates a Watcher that registers containers which have the SubdomainLabel
+func NewWatcher(runtime Runtime, registrar Registrar, logger *slog.Logger) Watcher {
+	return Watcher{
+		runtime:   runtime,
+		registrar: registrar,
+		logger:    logger.With("component", runtime.Name()),
+		routes:    map[string]route{},
+	}
+}
+
+// WithAllContainers also registers containers without the SubdomainLabel using their names
+func (w Watcher) WithAllContainers() Watcher {
+	w.all = true
+	return w
+}
+
+// WithForwarding always forwards published ports from an allocated IP instead of routing to container IPs
+func (w Watcher) WithForwarding() Watcher {
+	w.forward = true
+	return w
+}
+
+// Run registers the running containers and then keeps the routes updated until the context is done.
+// Routes registered by the Watcher are removed when it returns
+func (w Watcher) Run(ctx context.Context) error {
+	// subscribe before listing containers so changes in between are not missed
+	events, err := w.runtime.Events(ctx)
+	if err != nil {
+		return err
+	}
+	defer w.removeAll(context.WithoutCancel(ctx))
+
+	running, err := w.runtime.Containers(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range running {
+		w.update(ctx, c)
+	}
+
+	for e := range events {
+		w.handleEvent(ctx, e)
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return errEventStreamClosed
+}
+
+func (w Watcher) handleEvent(ctx context.Context, e Event) {
+	id := e.ContainerID()
+	if id == "" {
+		return
+	}
+
+	if e.Stopped() {
+		w.remove(ctx, id)
+		return
+	}
+
+	// inspect the container since a start or network change might change its IP
+	c, err := w.runtime.Container(ctx, id)
+	if err != nil {
+		w.logger.Error("error inspecting container", "container", id, "error", err)
+		return
+	}
+
+	w.update(ctx, c)
+}
+
+// subdomain gets the subdomain for the container from its label or name. Containers without the label are
+// skipped unless all containers are registered
+func (w Watcher) subdomain(c Container) (string, bool) {
+	subdomain, ok := c.Labels[SubdomainLabel]
+	if ok && subdomain != "" {
+		return subdomain, true
+	}
+
+	if w.all && c.Name != "" {
+		return c.Name, true
+	}
+
+	return "", false
+}
+
+// update registers the container's route if it is new or the IP changed, and removes it if the container
+// is no longer reachable
+func (w Watcher) update(ctx context.Context, c Container) {
+	subdomain, ok := w.subdomain(c)
+	if !ok {
+		// the label might have been removed from a container that was registered
+		w.remove(ctx, c.ID)
+		return
+	}
+
+	if !c.Running || c.IP == "" {
+		w.remove(ctx, c.ID)
+		return
+	}
+
+	existing, ok := w.routes[c.ID]
+	if ok && existing.subdomain == subdomain && existing.ip == c.IP {
+		return
+	}
+
+	if ok && existing.subdomain != subdomain {
+		w.remove(ctx, c.ID)
+		ok = false
+	}
+
+	// a forwarded container keeps its allocated IP when its own IP changes since releasing it happens in the
+	// background, so the subdomain might still be in use if it was allocated again
+	if ok && existing.forwarded != nil {
+		err := w.restartForwarding(existing.forwarded, c)
+		if err != nil {
+			w.logger.Error("error forwarding to container", "container", c.Name, "subdomain", subdomain, "error", err)
+			w.remove(ctx, c.ID)
+			return
+		}
+
+		existing.ip = c.IP
+		w.routes[c.ID] = existing
+		w.logger.Info("updated forwarding to container", "container", c.Name, "subdomain", subdomain, "ip", c.IP)
+		return
+	}
+
+	r := route{subdomain: subdomain, ip: c.IP}
+	var err error
+	if !w.forward && c.Reachable(ctx) {
+		err = w.registrar.RegisterFallback(ctx, subdomain, c.IP, 0)
+	} else {
+		r.forwarded, err = w.startForwarding(ctx, c, subdomain)
+	}
+	if err != nil {
+		w.logger.Error("error registering container", "container", c.Name, "subdomain", subdomain, "error", err)
+		return
+	}
+
+	w.routes[c.ID] = r
+	w.logger.Info("registered container", "container", c.Name, "subdomain", subdomain, "ip", c.IP, "forwarded", r.forwarded != nil)
+}
+
+// startForwarding allocates an IP for the subdomain and forwards the container's published ports from it
+func (w Watcher) startForwarding(ctx context.Context, c Container, subdomain string) (*forwardedRoute, error) {
+	allocCtx, release := context.WithCancel(ctx)
+	ip, err := allocate(allocCtx, w.registrar, c, subdomain)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	f := &forwardedRoute{ip: ip, ctx: allocCtx, release: release}
+	err = w.restartForwarding(f, c)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// restartForwarding stops forwarding if it was started and forwards the container's published ports from the
+// allocated IP, since they might have changed too
+func (w Watcher) restartForwarding(f *forwardedRoute, c Container) error {
+	if f.stop != nil {
+		f.stop()
+		<-f.stopped
+	}
+
+	forwardCtx, stop := context.WithCancel(f.ctx)
+	stopped, err := forwardPorts(forwardCtx, f.ip, c, w.logger)
+	if err != nil {
+		stop()
+		return fmt.Errorf("error forwarding ports: %w", err)
+	}
+
+	f.stop, f.stopped = stop, stopped
+	return nil
+}
+
+// remove removes the container's route if it was registered by the Watcher
+func (w Watcher) remove(ctx context.Context, id string) {
+	r, ok := w.routes[id]
+	if !ok {
+		return
+	}
+	delete(w.routes, id)
+
+	if r.forwarded != nil {
+		r.forwarded.release()
+		w.logger.Info("stopped forwarding to container", "subdomain", r.subdomain)
+		return
+	}
+
+	err := w.registrar.RemoveFallback(ctx, r.subdomain)
+	if err != nil {
+		w.logger.Error("error removing container route", "subdomain", r.subdomain, "error", err)
+		return
+	}
+
+	w.logger.Info("removed container route", "subdomain", r.subdomain)
+}
+
+func (w Watcher) removeAll(ctx context.Context) {
+	for id := range w.routes {
+		w.remove(ctx, id)
+	}
+}
@@ -0,0 +1,446 @@
+package containers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDocker serves the parts of the Docker Engine API used by the Watcher on a Unix socket
+type fakeDocker struct {
+	mu         sync.Mutex
+	containers map[string]fakeContainer
+	events     chan Event
+	// subscribed is closed when the events stream is opened
+	subscribed chan struct{}
+	socket     string
+}
+
+type fakeContainer struct {
+	name, subdomain, network, ip string
+	// port is published on the host at hostPort if it is set
+	port, hostPort int
+}
+
+// listPorts are the container's ports in the format used to list containers
+func (c fakeContainer) listPorts() []map[string]any {
+	if c.port == 0 {
+		return nil
+	}
+	return []map[string]any{{"IP": "127.0.0.1", "PrivatePort": c.port, "PublicPort": c.hostPort, "Type": "tcp"}}
+}
+
+// inspectPorts are the container's ports in the format used to inspect a container
+func (c fakeContainer) inspectPorts() map[string]any {
+	if c.port == 0 {
+		return nil
+	}
+	return map[string]any{
+		fmt.Sprintf("%d/tcp", c.port): []map[string]string{{"HostIp": "127.0.0.1", "HostPort": fmt.Sprint(c.hostPort)}},
+	}
+}
+
+func newFakeDocker(t *testing.T) *fakeDocker {
+	t.Helper()
+
+	// Unix socket paths have a short length limit, so t.TempDir might be too long
+	dir, err := os.MkdirTemp("", "goblin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	f := &fakeDocker{
+		containers: map[string]fakeContainer{},
+		events:     make(chan Event),
+		subscribed: make(chan struct{}),
+		socket:     filepath.Join(dir, "docker.sock"),
+	}
+
+	l, err := net.Listen("unix", f.socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /containers/json", f.listHandler)
+	mux.HandleFunc("GET /containers/{id}/json", f.inspectHandler)
+	mux.HandleFunc("GET /events", f.eventsHandler)
+
+	server := httptest.NewUnstartedServer(mux)
+	server.Listener = l
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return f
+}
+
+func (f *fakeDocker) set(id string, c fakeContainer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.containers[id] = c
+}
+
+func (f *fakeDocker) delete(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.containers, id)
+}
+
+func (f *fakeDocker) send(t *testing.T, eventType, action, id string) {
+	t.Helper()
+
+	var e Event
+	e.Type = eventType
+	e.Action = action
+	e.Actor.ID = id
+	if eventType == "network" {
+		e.Actor.ID = "network-id"
+		e.Actor.Attributes = map[string]string{"container": id}
+	}
+
+	select {
+	case f.events <- e:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out sending %s event", action)
+	}
+}
+
+func (f *fakeDocker) listHandler(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := []map[string]any{}
+	for id, c := range f.containers {
+		result = append(result, map[string]any{
+			"Id":              id,
+			"Names":           []string{"/" + c.name},
+			"Labels":          map[string]string{SubdomainLabel: c.subdomain},
+			"Ports":           c.listPorts(),
+			"NetworkSettings": map[string]any{"Networks": map[string]any{c.network: map[string]string{"IPAddress": c.ip}}},
+		})
+	}
+
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeDocker) inspectHandler(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	c, ok := f.containers[r.PathValue("id")]
+	f.mu.Unlock()
+	if !ok {
+		http.Error(w, `{"message": "no such container"}`, http.StatusNotFound)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"Id":     r.PathValue("id"),
+		"Name":   "/" + c.name,
+		"Config": map[string]any{"Labels": map[string]string{SubdomainLabel: c.subdomain}},
+		"State":  map[string]bool{"Running": true},
+		"NetworkSettings": map[string]any{
+			"Networks": map[string]any{c.network: map[string]string{"IPAddress": c.ip}},
+			"Ports":    c.inspectPorts(),
+		},
+	})
+}
+
+func (f *fakeDocker) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	close(f.subscribed)
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case e := <-f.events:
+			_ = encoder.Encode(e)
+			w.(http.Flusher).Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// fakeRegistrar keeps fallback routes and allocations in memory. Allocated IPs are always 127.0.0.1
+type fakeRegistrar struct {
+	mu        sync.Mutex
+	fallbacks map[string]string
+	// allocations are the contexts of each allocation, which is released when it is done
+	allocations map[string][]context.Context
+}
+
+func (r *fakeRegistrar) GetIP(ctx context.Context, subdomain string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.allocations == nil {
+		return "", fmt.Errorf("unexpected GetIP")
+	}
+
+	allocations := r.allocations[subdomain]
+	if len(allocations) > 0 && allocations[len(allocations)-1].Err() == nil {
+		return "", fmt.Errorf("subdomain %q is already in use", subdomain)
+	}
+	r.allocations[subdomain] = append(allocations, ctx)
+
+	return "127.0.0.1", nil
+}
+
+// allocated gets the contexts of each allocation for the subdomain
+func (r *fakeRegistrar) allocated(subdomain string) []context.Context {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.allocations[subdomain])
+}
+
+func (r *fakeRegistrar) RegisterFallback(_ context.Context, subdomain, address string, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallbacks[subdomain] = address
+	return nil
+}
+
+func (r *fakeRegistrar) RemoveFallback(_ context.Context, subdomain string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.fallbacks, subdomain)
+	return nil
+}
+
+// waitForFallbacks waits until the registered fallbacks match the expected routes
+func (r *fakeRegistrar) waitForFallbacks(t *testing.T, expected map[string]string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mu.Lock()
+		actual := maps.Clone(r.fallbacks)
+		r.mu.Unlock()
+
+		if maps.Equal(actual, expected) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected fallbacks %v, got %v", expected, actual)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	docker := newFakeDocker(t)
+	docker.set("existing", fakeContainer{name: "existing", subdomain: "existing", network: "bridge", ip: "172.17.0.2"})
+
+	registrar := &fakeRegistrar{fallbacks: map[string]string{}}
+	watcher := NewWatcher(NewDocker(docker.socket), registrar, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- watcher.Run(ctx)
+	}()
+
+	select {
+	case <-docker.subscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for events subscription")
+	}
+
+	t.Run("RegisterRunningContainers", func(t *testing.T) {
+		registrar.waitForFallbacks(t, map[string]string{"existing": "172.17.0.2"})
+	})
+
+	t.Run("StartRegisters", func(t *testing.T) {
+		docker.set("web", fakeContainer{name: "web", subdomain: "web", network: "bridge", ip: "172.17.0.3"})
+		docker.send(t, "container", "start", "web")
+
+		registrar.waitForFallbacks(t, map[string]string{"existing": "172.17.0.2", "web": "172.17.0.3"})
+	})
+
+	t.Run("NetworkChangeUpdatesIP", func(t *testing.T) {
+		docker.set("web", fakeContainer{name: "web", subdomain: "web", network: "app", ip: "172.18.0.3"})
+		docker.send(t, "network", "connect", "web")
+
+		registrar.waitForFallbacks(t, map[string]string{"existing": "172.17.0.2", "web": "172.18.0.3"})
+	})
+
+	t.Run("SubdomainChangeRemovesOldRoute", func(t *testing.T) {
+		docker.set("web", fakeContainer{name: "web", subdomain: "site", network: "app", ip: "172.18.0.3"})
+		docker.send(t, "container", "start", "web")
+
+		registrar.waitForFallbacks(t, map[string]string{"existing": "172.17.0.2", "site": "172.18.0.3"})
+	})
+
+	t.Run("DieRemoves", func(t *testing.T) {
+		docker.delete("web")
+		docker.send(t, "container", "die", "web")
+
+		registrar.waitForFallbacks(t, map[string]string{"existing": "172.17.0.2"})
+	})
+
+	t.Run("StopRemovesAll", func(t *testing.T) {
+		cancel()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for watcher to stop")
+		}
+
+		registrar.waitForFallbacks(t, map[string]string{})
+	})
+}
+
+// serveText starts a TCP server that writes the text to each connection and returns its port
+func serveText(t *testing.T, text string) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = io.WriteString(conn, text)
+			conn.Close()
+		}
+	}()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// freePort finds a port that isn't used on 127.0.0.1
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// waitForText waits until connecting to the address reads the expected text
+func waitForText(t *testing.T, addr, expected string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	var actual string
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			data, _ := io.ReadAll(conn)
+			conn.Close()
+			actual = string(data)
+			if actual == expected {
+				return
+			}
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %q from %s, got %q and error %v", expected, addr, actual, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatcherForwarding(t *testing.T) {
+	port := freePort(t)
+	addr := net.JoinHostPort("127.0.0.1", fmt.Sprint(port))
+
+	docker := newFakeDocker(t)
+	docker.set("db", fakeContainer{
+		name: "db", subdomain: "db", network: "bridge", ip: "172.17.0.5", port: port, hostPort: serveText(t, "first"),
+	})
+
+	registrar := &fakeRegistrar{fallbacks: map[string]string{}, allocations: map[string][]context.Context{}}
+	watcher := NewWatcher(NewDocker(docker.socket), registrar, slog.New(slog.NewTextHandler(io.Discard, nil))).
+		WithForwarding()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- watcher.Run(ctx)
+	}()
+
+	select {
+	case <-docker.subscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for events subscription")
+	}
+
+	t.Run("ForwardsPublishedPort", func(t *testing.T) {
+		waitForText(t, addr, "first")
+	})
+
+	t.Run("IPChangeKeepsAllocation", func(t *testing.T) {
+		docker.set("db", fakeContainer{
+			name: "db", subdomain: "db", network: "app", ip: "172.18.0.5", port: port, hostPort: serveText(t, "second"),
+		})
+		docker.send(t, "network", "connect", "db")
+
+		// forwarding is restarted with the new published port
+		waitForText(t, addr, "second")
+
+		allocations := registrar.allocated("db")
+		if len(allocations) != 1 {
+			t.Fatalf("expected 1 allocation, got %d", len(allocations))
+		}
+		if allocations[0].Err() != nil {
+			t.Error("expected IP to stay allocated")
+		}
+	})
+
+	t.Run("DieReleases", func(t *testing.T) {
+		docker.delete("db")
+		docker.send(t, "container", "die", "db")
+
+		deadline := time.Now().Add(5 * time.Second)
+		for registrar.allocated("db")[0].Err() == nil {
+			if time.Now().After(deadline) {
+				t.Fatal("expected IP to be released")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		registrar.waitForFallbacks(t, map[string]string{})
+	})
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for watcher to stop")
+	}
+}
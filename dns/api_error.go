@@ -0,0 +1,80 @@
+package dns
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+// Error codes are used in API error responses so clients can handle errors without parsing messages
+const (
+	ErrorCodeSubdomainInUse    = "subdomain_in_use"
+	ErrorCodeNoAvailableIPs    = "no_available_ips"
+	ErrorCodeNotFound          = "not_found"
+	ErrorCodeInvalidSubdomain  = "invalid_subdomain"
+	ErrorCodeReservedSubdomain = "reserved_subdomain"
+	ErrorCodeConfiguredRoute   = "configured_route"
+	ErrorCodeInvalidRequest    = "invalid_request"
+	ErrorCodeUnauthorized      = "unauthorized"
+	ErrorCodeInternal          = "internal"
+)
+
+// errorCodes maps codes back to the errors that they are created from
+var errorCodes = map[string]error{
+	ErrorCodeSubdomainInUse:    ErrSubdomainInUse,
+	ErrorCodeNoAvailableIPs:    ErrNoAvailableIPs,
+	ErrorCodeNotFound:          ErrNotFound,
+	ErrorCodeInvalidSubdomain:  ErrInvalidSubdomain,
+	ErrorCodeReservedSubdomain: ErrReservedSubdomain,
+	ErrorCodeConfiguredRoute:   ErrConfiguredRoute,
+}
+
+// APIError is the body of error responses from the API. It can be checked with errors.Is for the errors in
+// this package, and with errors.As for errors.UserFixableError when it has Instructions
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Instructions explain how to fix the error when it is caused by the server's configuration
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// ErrorResponse wraps an APIError in the response body
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+func (e APIError) Error() string {
+	return e.Message
+}
+
+func (e APIError) Unwrap() []error {
+	var errs []error
+	if err, ok := errorCodes[e.Code]; ok {
+		errs = append(errs, err)
+	}
+	if e.Instructions != "" {
+		errs = append(errs, errors.NewUserFixableError(errors.New(e.Message), e.Instructions))
+	}
+	return errs
+}
+
+// readAPIError reads an APIError from an unsuccessful response. If the body is not an error response, the
+// error includes the status and body instead
+func readAPIError(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unexpected response status: %d", resp.StatusCode)
+	}
+
+	var errResp ErrorResponse
+	err = json.Unmarshal(body, &errResp)
+	if err != nil || errResp.Error.Code == "" {
+		return fmt.Errorf("unexpected response status: %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return errResp.Error
+}
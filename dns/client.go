@@ -3,38 +3,124 @@ package dns
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/calvinmclean/goblin/auth"
 )
 
+// unixSocketScheme is the prefix for server addresses that are a path to a Unix socket
+const unixSocketScheme = "unix://"
+
 // Client is used to get IPs from the server over HTTP
 type Client struct {
-	addr string
+	addr       string
+	token      string
+	httpClient *http.Client
 }
 
+// NewHTTPClient creates a Client for the server at addr, which is a host and port or the path to a Unix socket
+// like unix:///path/to/goblin.sock. If the API token file exists, its token is used to authenticate requests
 func NewHTTPClient(addr string) (Client, error) {
-	return Client{addr}, nil
+	httpClient := http.DefaultClient
+	if socket, ok := strings.CutPrefix(addr, unixSocketScheme); ok {
+		if socket == "" {
+			return Client{}, fmt.Errorf("missing socket path in address %q", addr)
+		}
+
+		// the host is not used to connect, but is required for the request URL
+		addr = "goblin"
+		httpClient = &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		}
+	}
+
+	token, err := auth.ClientToken()
+	if err != nil {
+		return Client{}, err
+	}
+
+	return Client{addr, token, httpClient}, nil
+}
+
+// WithToken returns a copy of the Client that uses the token to authenticate requests
+func (c Client) WithToken(token string) Client {
+	c.token = token
+	return c
+}
+
+// DefaultSocketPath is the default location of the Unix socket for the server's API. It is in the user's runtime
+// directory, or a user-specific directory in the temp directory if XDG_RUNTIME_DIR is not set
+func DefaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "goblin", "goblin.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("goblin-%d", os.Getuid()), "goblin.sock")
+}
+
+// UnixSocketAddr creates an address for NewHTTPClient that connects to the Unix socket
+func UnixSocketAddr(path string) string {
+	return unixSocketScheme + path
 }
 
 func (c Client) GetIP(ctx context.Context, subdomain string) (string, error) {
+	return c.allocate(ctx, subdomain, false)
+}
+
+// GetPendingIP allocates an IP that DNS doesn't use instead of the subdomain's fallback route until SetReady
+// is called
+func (c Client) GetPendingIP(ctx context.Context, subdomain string) (string, error) {
+	return c.allocate(ctx, subdomain, true)
+}
+
+// SetReady tells the server that the subdomain's pending IP is ready to be used by DNS
+func (c Client) SetReady(ctx context.Context, subdomain string) error {
+	return c.doJSON(ctx, http.MethodPost, fmt.Sprintf("ready/%s", subdomain), http.StatusNoContent, nil)
+}
+
+// SetPending tells the server to use the subdomain's fallback route again until SetReady is called, while the IP
+// stays allocated
+func (c Client) SetPending(ctx context.Context, subdomain string) error {
+	return c.doJSON(ctx, http.MethodDelete, fmt.Sprintf("ready/%s", subdomain), http.StatusNoContent, nil)
+}
+
+func (c Client) allocate(ctx context.Context, subdomain string, pending bool) (string, error) {
 	u := url.URL{
 		Scheme: "http",
 		Host:   c.addr,
 		Path:   fmt.Sprintf("allocate/%s", subdomain),
 	}
+	if pending {
+		u.RawQuery = url.Values{"pending": {"true"}}.Encode()
+	}
 
-	resp, err := http.Post(u.String(), "", http.NoBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), http.NoBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to send request to server: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusCreated {
-		fmt.Println("STTUS", resp.StatusCode)
-		printResponseBody(resp)
-		return "", fmt.Errorf("unexpected response status: %d", resp.StatusCode)
+		defer resp.Body.Close()
+		return "", readAPIError(resp)
 	}
 
 	ip, err := bufio.NewReader(resp.Body).ReadString('\n')
@@ -55,9 +141,14 @@ func (c Client) GetIP(ctx context.Context, subdomain string) (string, error) {
 	return ip, nil
 }
 
-func (c Client) RegisterFallback(subdomain, address string) error {
+// RegisterFallback registers a fallback route with the server. If expiresIn is not zero, the server
+// removes the route after that duration
+func (c Client) RegisterFallback(ctx context.Context, subdomain, address string, expiresIn time.Duration) error {
 	vals := url.Values{}
 	vals.Add("address", address)
+	if expiresIn > 0 {
+		vals.Add("expire", expiresIn.String())
+	}
 	u := url.URL{
 		Scheme:   "http",
 		Host:     c.addr,
@@ -65,24 +156,230 @@ func (c Client) RegisterFallback(subdomain, address string) error {
 		RawQuery: vals.Encode(),
 	}
 
-	resp, err := http.Post(u.String(), "", http.NoBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), http.NoBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send request to server: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		printResponseBody(resp)
-		return fmt.Errorf("unexpected response status: %d", resp.StatusCode)
+		return readAPIError(resp)
 	}
 
 	return nil
 }
 
-func printResponseBody(r *http.Response) {
-	body, err := io.ReadAll(r.Body)
+// Records gets all IP allocations from the server
+func (c Client) Records(ctx context.Context) ([]Record, error) {
+	var result []Record
+	err := c.doJSON(ctx, http.MethodGet, "records", http.StatusOK, &result)
+	return result, err
+}
+
+// Record gets the IP allocation for a subdomain from the server
+func (c Client) Record(ctx context.Context, subdomain string) (Record, error) {
+	var result Record
+	err := c.doJSON(ctx, http.MethodGet, fmt.Sprintf("records/%s", subdomain), http.StatusOK, &result)
+	return result, err
+}
+
+// Lookup gets the IP that DNS answers with for a subdomain from the server
+func (c Client) Lookup(ctx context.Context, subdomain string) (Lookup, error) {
+	var result Lookup
+	err := c.doJSON(ctx, http.MethodGet, fmt.Sprintf("lookup/%s", subdomain), http.StatusOK, &result)
+	return result, err
+}
+
+// Info gets the server's DNS configuration
+func (c Client) Info(ctx context.Context) (Info, error) {
+	var result Info
+	err := c.doJSON(ctx, http.MethodGet, "info", http.StatusOK, &result)
+	return result, err
+}
+
+// Fallbacks gets all fallback routes from the server
+func (c Client) Fallbacks(ctx context.Context) ([]Fallback, error) {
+	var result []Fallback
+	err := c.doJSON(ctx, http.MethodGet, "fallbacks", http.StatusOK, &result)
+	return result, err
+}
+
+// Fallback gets the fallback route for a subdomain from the server
+func (c Client) Fallback(ctx context.Context, subdomain string) (Fallback, error) {
+	var result Fallback
+	err := c.doJSON(ctx, http.MethodGet, fmt.Sprintf("fallbacks/%s", subdomain), http.StatusOK, &result)
+	return result, err
+}
+
+// RemoveFallback removes the fallback route for a subdomain from the server
+func (c Client) RemoveFallback(ctx context.Context, subdomain string) error {
+	return c.doJSON(ctx, http.MethodDelete, fmt.Sprintf("fallbacks/%s", subdomain), http.StatusNoContent, nil)
+}
+
+// IPPool gets the usage of IPs from the server
+func (c Client) IPPool(ctx context.Context) (IPPool, error) {
+	var result IPPool
+	err := c.doJSON(ctx, http.MethodGet, "ips", http.StatusOK, &result)
+	return result, err
+}
+
+// Status gets a summary of allocations and fallback routes from the server
+func (c Client) Status(ctx context.Context) (Status, error) {
+	var result Status
+	err := c.doJSON(ctx, http.MethodGet, "status", http.StatusOK, &result)
+	return result, err
+}
+
+// Watch streams events from the server. The channel is closed when the context is done or the
+// connection to the server is lost
+func (c Client) Watch(ctx context.Context) (<-chan Event, error) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   c.addr,
+		Path:   "events",
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
 	if err != nil {
-		fmt.Printf("error reading response body: %v\n", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to server: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, readAPIError(resp)
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			// only the data lines are needed since they include the event type
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+
+			var event Event
+			err := json.Unmarshal([]byte(data), &event)
+			if err != nil {
+				continue
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// StreamLogs sends lines from the reader to the server, which keeps recent logs for each subdomain.
+// It returns when the reader is closed or the context is done
+func (c Client) StreamLogs(ctx context.Context, subdomain string, r io.Reader) error {
+	u := url.URL{
+		Scheme: "http",
+		Host:   c.addr,
+		Path:   fmt.Sprintf("logs/%s", subdomain),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), r)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return readAPIError(resp)
+	}
+
+	return nil
+}
+
+// Queries gets recent DNS queries from the server's query log, starting with the newest
+func (c Client) Queries(ctx context.Context, f QueryFilter) ([]QueryLogEntry, error) {
+	vals := url.Values{}
+	for key, val := range map[string]string{"subdomain": f.Subdomain, "source": f.Source, "type": f.Type} {
+		if val != "" {
+			vals.Set(key, val)
+		}
+	}
+	if !f.Since.IsZero() {
+		vals.Set("since", f.Since.Format(time.RFC3339))
+	}
+	if f.Limit > 0 {
+		vals.Set("limit", strconv.Itoa(f.Limit))
+	}
+
+	var result []QueryLogEntry
+	err := c.doJSON(ctx, http.MethodGet, "queries?"+vals.Encode(), http.StatusOK, &result)
+	return result, err
+}
+
+// doJSON makes a request to the server and parses the JSON response into out, unless it is nil
+func (c Client) doJSON(ctx context.Context, method, path string, expectedStatus int, out any) error {
+	path, rawQuery, _ := strings.Cut(path, "?")
+	u := url.URL{
+		Scheme:   "http",
+		Host:     c.addr,
+		Path:     path,
+		RawQuery: rawQuery,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), http.NoBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		return readAPIError(resp)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
+
+// do sends the request with the Client's token
+func (c Client) do(req *http.Request) (*http.Response, error) {
+	auth.SetToken(req, c.token)
+	if c.httpClient == nil {
+		return http.DefaultClient.Do(req)
 	}
-	fmt.Println(string(body))
+	return c.httpClient.Do(req)
 }
@@ -0,0 +1,62 @@
+package dns
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+func TestClientRegisterFallback(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/register/db" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		query = r.URL.RawQuery
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewHTTPClient(strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		expiresIn     time.Duration
+		expectedQuery string
+		expectedError error
+	}{
+		{"NoExpire", context.Background(), 0, "address=192.168.1.10", nil},
+		{"Expire", context.Background(), time.Minute, "address=192.168.1.10&expire=1m0s", nil},
+		{"CanceledContext", canceled, 0, "", context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query = ""
+
+			err := client.RegisterFallback(tt.ctx, "db", "192.168.1.10", tt.expiresIn)
+			if tt.expectedError == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectedError != nil && !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected %v, got %v", tt.expectedError, err)
+			}
+
+			if query != tt.expectedQuery {
+				t.Errorf("expected query %q, got %q", tt.expectedQuery, query)
+			}
+		})
+	}
+}
@@ -2,10 +2,12 @@ package dns
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"net"
 	"strings"
+	"time"
 )
 
 func (m Manager) RunDNS(ctx context.Context) error {
@@ -38,69 +40,129 @@ func (m Manager) RunDNS(ctx context.Context) error {
 	}
 }
 
-func getSubdomain(d string) string {
-	parts := strings.Split(d, ".")
-	if len(parts) == 0 {
-		return ""
-	}
+// getSubdomain removes the top-level domain from a lowercase domain name, so the subdomain can have multiple labels
+func getSubdomain(d, topLevelDomain string) string {
+	subdomain := strings.TrimSuffix(d, "."+topLevelDomain)
 	// Sometimes the web-browser throws a 'www.' in front of the domain
-	if parts[0] == "www" {
-		if len(parts) >= 2 {
-			return parts[1]
-		}
+	if trimmed, ok := strings.CutPrefix(subdomain, "www."); ok {
+		return trimmed
 	}
-	return parts[0]
+	return subdomain
 }
 
-func (m Manager) handleDNSRequest(conn net.PacketConn, clientAddr net.Addr, request []byte) error {
+func (m Manager) handleDNSRequest(conn net.PacketConn, clientAddr net.Addr, request []byte) (err error) {
+	start := time.Now()
+	result := queryResultError
+	entry := QueryLogEntry{
+		Time:   start,
+		Client: clientAddr.String(),
+	}
+	defer func() {
+		m.metrics.observeQuery(result, start)
+
+		entry.Source = result
+		entry.Latency = Duration(time.Since(start))
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		logErr := m.queries.add(entry)
+		if logErr != nil {
+			m.logger.Error("error writing query log", "error", logErr)
+		}
+	}()
+
 	if len(request) < 12 {
 		return fmt.Errorf("invalid length for request: %d", len(request))
 	}
 
 	query := request[12:]
-	domain := parseDomain(query)
+	domain, qtype, err := parseQuestion(query)
+	if err != nil {
+		return fmt.Errorf("invalid question: %w", err)
+	}
+	entry.Name = domain
+	entry.Type = queryTypeName(qtype)
 
-	m.logger.Info("received DNS request", "domain", domain)
+	m.logger.Debug("received DNS request", "domain", domain)
 
-	if !strings.HasSuffix(domain, m.Domain) {
+	// DNS names are case-insensitive and subdomains are stored in lowercase
+	domain = strings.ToLower(domain)
+	if !strings.HasSuffix(domain, "."+m.Domain) {
 		// Ignore this DNS domain
 		if domain == "_dns.resolver.arpa" {
+			result = queryResultIgnored
 			return nil
 		}
 		return fmt.Errorf("unexpected domain: %s", domain)
 	}
 
-	subdomain := getSubdomain(domain)
+	subdomain := getSubdomain(domain, m.Domain)
+	entry.Subdomain = subdomain
 
-	rec, ok := m.subdomains[subdomain]
-	if !ok || !rec.isActive() {
-		// if a domain is not registered or is registered but un-allocated, check for fallback routes
-		m.logger.Debug("checking for fallback routes")
-		var err error
-		rec, err = m.handleFallbackRoutes(subdomain)
-		if err != nil {
-			return fmt.Errorf("error handling fallback routes: %w", err)
-		}
-		if rec == nil {
-			return fmt.Errorf("no record found for subdomain %q", subdomain)
-		}
+	rec, result, err := m.resolve(subdomain)
+	if err != nil {
+		m.events.publish(Event{Type: EventQueryMissed, Subdomain: subdomain, Error: err.Error()})
+		return err
 	}
-	m.logger.Info("responding with ip", "subdomain", subdomain, "ip", rec.ip.String())
+	entry.Answer = rec.ip.String()
+	m.logger.Debug("responding with ip", "subdomain", subdomain, "ip", rec.ip.String())
+	m.events.publish(Event{Type: EventQueryServed, Subdomain: subdomain, IP: rec.ip.String()})
 
-	response := m.createDNSResponse(request, rec.ip)
+	response := m.createDNSResponse(request, rec.ip, rec.ttl)
 	if response == nil {
+		result = queryResultError
 		return errors.New("unexpected empty response")
 	}
 
-	_, err := conn.WriteTo(response, clientAddr)
+	_, err = conn.WriteTo(response, clientAddr)
 	if err != nil {
+		result = queryResultError
 		return fmt.Errorf("error writing response: %w", err)
 	}
 
 	return nil
 }
 
-func (m Manager) createDNSResponse(request []byte, ip net.IP) []byte {
+// resolve finds the record that DNS answers with for the subdomain. It is the allocated IP, or the fallback route
+// if the subdomain isn't allocated or isn't ready. ErrNotFound is returned if there is neither, so a pending IP is
+// never used before it's ready
+func (m Manager) resolve(subdomain string) (*record, string, error) {
+	rec, ok := m.activeRecord(subdomain)
+	if ok && !rec.pending {
+		return rec, queryResultLocal, nil
+	}
+
+	// if a domain is not registered, is registered but un-allocated, or isn't ready, check for fallback routes
+	m.logger.Debug("checking for fallback routes", "subdomain", subdomain)
+	rec, err := m.handleFallbackRoutes(subdomain)
+	if err != nil {
+		return nil, queryResultError, fmt.Errorf("error handling fallback routes: %w", err)
+	}
+	if rec == nil && ok {
+		return nil, queryResultMiss, fmt.Errorf("%w: ready record or fallback route for subdomain %q", ErrNotFound, subdomain)
+	}
+	if rec == nil {
+		return nil, queryResultMiss, fmt.Errorf("%w: record or fallback route for subdomain %q", ErrNotFound, subdomain)
+	}
+
+	return rec, queryResultFallback, nil
+}
+
+// activeRecord returns a copy of the subdomain's record if it is currently allocated
+func (m Manager) activeRecord(subdomain string) (*record, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rec, ok := m.subdomains[subdomain]
+	if !ok || !rec.isActive() {
+		return nil, false
+	}
+
+	recCopy := *rec
+	return &recCopy, true
+}
+
+func (m Manager) createDNSResponse(request []byte, ip net.IP, ttl time.Duration) []byte {
 	// Create a DNS response based on the request
 	response := make([]byte, len(request)+16)
 	copy(response, request)
@@ -125,10 +187,8 @@ func (m Manager) createDNSResponse(request []byte, ip net.IP) []byte {
 	response[offset+3] = 0x01
 	response[offset+4] = 0x00 // Class IN
 	response[offset+5] = 0x01
-	response[offset+6] = 0x00 // TTL
-	response[offset+7] = 0x00
-	response[offset+8] = 0x00
-	response[offset+9] = 0x00  // 0 seconds TTL
+	// TTL in seconds
+	binary.BigEndian.PutUint32(response[offset+6:offset+10], uint32(ttl.Seconds()))
 	response[offset+10] = 0x00 // Data length
 	response[offset+11] = 0x04
 	response[offset+12] = ip[0]
@@ -153,15 +213,55 @@ func ipToBytes(ip string) net.IP {
 	return ipBytes
 }
 
-func parseDomain(query []byte) string {
+// parseQuestion reads the domain name and query type from the question section of a request
+func parseQuestion(query []byte) (string, uint16, error) {
 	var domainParts []string
 	i := 0
-	for query[i] != 0 {
-		length := query[i]
+	for {
+		if i >= len(query) {
+			return "", 0, errors.New("unexpected end of domain name")
+		}
+
+		length := int(query[i])
 		i++
-		part := string(query[i : i+int(length)])
-		domainParts = append(domainParts, part)
-		i += int(length)
+		if length == 0 {
+			break
+		}
+
+		if i+length > len(query) {
+			return "", 0, errors.New("domain label is too long")
+		}
+		domainParts = append(domainParts, string(query[i:i+length]))
+		i += length
+	}
+
+	if i+2 > len(query) {
+		return "", 0, errors.New("missing query type")
+	}
+	qtype := binary.BigEndian.Uint16(query[i : i+2])
+
+	return strings.Join(domainParts, "."), qtype, nil
+}
+
+var queryTypeNames = map[uint16]string{
+	1:   "A",
+	2:   "NS",
+	5:   "CNAME",
+	6:   "SOA",
+	12:  "PTR",
+	15:  "MX",
+	16:  "TXT",
+	28:  "AAAA",
+	33:  "SRV",
+	64:  "SVCB",
+	65:  "HTTPS",
+	255: "ANY",
+}
+
+func queryTypeName(qtype uint16) string {
+	name, ok := queryTypeNames[qtype]
+	if !ok {
+		return fmt.Sprintf("TYPE%d", qtype)
 	}
-	return strings.Join(domainParts, ".")
+	return name
 }
@@ -0,0 +1,106 @@
+package dns
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+// newTestManager creates a Manager without checking the system's resolver and IP aliases
+func newTestManager(t *testing.T) Manager {
+	t.Helper()
+
+	m, err := NewUnchecked(Config{Domain: "goblin", Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+// addRecord allocates the IP to the subdomain without running a plugin
+func (m Manager) addRecord(subdomain, ip string, pending bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec := &record{ip: net.ParseIP(ip).To4(), subdomain: subdomain, allocatedAt: time.Now(), pending: pending}
+	m.subdomains[subdomain] = rec
+	m.allocatedIPs[ip] = rec
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name           string
+		pending        bool
+		fallback       string
+		expectedIP     string
+		expectedResult string
+		expectedErr    error
+	}{
+		{"Ready", false, "", "10.0.0.4", queryResultLocal, nil},
+		{"ReadyWithFallback", false, "192.168.1.10", "10.0.0.4", queryResultLocal, nil},
+		{"PendingWithFallback", true, "192.168.1.10", "192.168.1.10", queryResultFallback, nil},
+		{"PendingWithoutFallback", true, "", "", queryResultMiss, ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t)
+			m.addRecord("app", "10.0.0.4", tt.pending)
+			if tt.fallback != "" {
+				err := m.RegisterFallback("app", tt.fallback, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rec, result, err := m.resolve("app")
+			if result != tt.expectedResult {
+				t.Errorf("expected result %q, got %q", tt.expectedResult, result)
+			}
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+				}
+				if rec != nil {
+					t.Errorf("expected no record, got %s", rec.ip)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.ip.String() != tt.expectedIP {
+				t.Errorf("expected IP %s, got %s", tt.expectedIP, rec.ip)
+			}
+		})
+	}
+}
+
+func TestSetReady(t *testing.T) {
+	m := newTestManager(t)
+	m.addRecord("app", "10.0.0.4", true)
+
+	_, _, err := m.resolve("app")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before ready, got %v", err)
+	}
+
+	err = m.SetReady(context.Background(), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, result, err := m.resolve("app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != queryResultLocal || rec.ip.String() != "10.0.0.4" {
+		t.Errorf("expected local 10.0.0.4, got %s %s", result, rec.ip)
+	}
+}
@@ -6,8 +6,15 @@ import (
 )
 
 var (
-	ErrNoAvailableIPs = errors.New("no available IPs")
-	ErrSubdomainInUse = errors.New("subdomain already in-use")
+	ErrNoAvailableIPs   = errors.New("no available IPs")
+	ErrSubdomainInUse   = errors.New("subdomain already in-use")
+	ErrNotFound         = errors.New("not found")
+	ErrInvalidSubdomain = errors.New("invalid subdomain")
+	// ErrReservedSubdomain is returned when changing a route that Goblin uses internally, like the dashboard's
+	ErrReservedSubdomain = errors.New("subdomain is reserved")
+	// ErrConfiguredRoute is returned when removing a route from the fallback config, which would be restored when
+	// the config is reloaded
+	ErrConfiguredRoute = errors.New("route is set in the fallback config")
 )
 
 const (
@@ -0,0 +1,85 @@
+package dns
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EventType describes what changed in an Event
+type EventType string
+
+const (
+	EventAllocated          EventType = "allocated"
+	EventReady              EventType = "ready"
+	EventPending            EventType = "pending"
+	EventReleased           EventType = "released"
+	EventFallbackRegistered EventType = "fallback_registered"
+	EventFallbackRemoved    EventType = "fallback_removed"
+	EventQueryServed        EventType = "dns_query_served"
+	EventQueryMissed        EventType = "dns_query_missed"
+)
+
+// eventBufferSize is the number of events a slow subscriber can fall behind before events are dropped
+const eventBufferSize = 100
+
+// Event is published by the Manager when records or fallback routes change and when DNS queries are handled
+type Event struct {
+	Type      EventType `json:"type"`
+	Time      time.Time `json:"time"`
+	Subdomain string    `json:"subdomain"`
+	// IP is the allocated IP or the IP used in a DNS response
+	IP string `json:"ip,omitempty"`
+	// Target is the fallback route's target
+	Target string `json:"target,omitempty"`
+	// Error explains why a DNS query was missed
+	Error string `json:"error,omitempty"`
+}
+
+// eventBus sends events to all subscribers without blocking the publisher
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: map[chan Event]struct{}{}}
+}
+
+func (b *eventBus) publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		select {
+		case sub <- e:
+		default:
+			// drop the event instead of blocking everything on a slow subscriber
+		}
+	}
+}
+
+// Subscribe returns a channel that receives all events until the context is done
+func (m Manager) Subscribe(ctx context.Context) <-chan Event {
+	sub := make(chan Event, eventBufferSize)
+
+	m.events.mu.Lock()
+	m.events.subscribers[sub] = struct{}{}
+	m.events.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		m.events.mu.Lock()
+		delete(m.events.subscribers, sub)
+		m.events.mu.Unlock()
+
+		close(sub)
+	}()
+
+	return sub
+}
@@ -0,0 +1,266 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/calvinmclean/goblin/watch"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FallbackConfig is the structure of a fallback routes config file. For backwards-compatibility,
+// a file with a flat mapping of subdomain to target is also accepted and used as Routes
+type FallbackConfig struct {
+	Routes FallbackRoutes `json:"routes" yaml:"routes" toml:"routes"`
+	// Reservations map a subdomain to the IP it should always be allocated when it runs locally
+	Reservations map[string]string `json:"reservations,omitempty" yaml:"reservations" toml:"reservations"`
+}
+
+// Route configures where a subdomain is routed when it is not running locally
+type Route struct {
+	// Target is an IP, hostname, or URL of the remote destination
+	Target string `json:"target" yaml:"target" toml:"target"`
+	// TTL is used in DNS responses for this route. The default is 0 so changes take effect immediately
+	TTL Duration `json:"ttl,omitempty" yaml:"ttl" toml:"ttl"`
+	// Proxy customizes how the Target is resolved
+	Proxy *ProxyOptions `json:"proxy,omitempty" yaml:"proxy" toml:"proxy"`
+	// Metadata is not used by Goblin, but is useful for describing routes
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata" toml:"metadata"`
+}
+
+// ProxyOptions customizes the lookup of a Route's Target
+type ProxyOptions struct {
+	// Resolver is the address (host:port) of a DNS server used to look up the Target, like a VPN's DNS server
+	Resolver string `json:"resolver,omitempty" yaml:"resolver" toml:"resolver"`
+	// Timeout limits how long the lookup can take
+	Timeout Duration `json:"timeout,omitempty" yaml:"timeout" toml:"timeout"`
+}
+
+// Duration allows using strings like "30s" in config files
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(data []byte) error {
+	parsed, err := time.ParseDuration(string(data))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// UnmarshalJSON allows a Route to be configured with only the target string
+func (r *Route) UnmarshalJSON(data []byte) error {
+	var target string
+	if json.Unmarshal(data, &target) == nil {
+		*r = Route{Target: target}
+		return nil
+	}
+
+	type route Route
+	return json.Unmarshal(data, (*route)(r))
+}
+
+// UnmarshalYAML allows a Route to be configured with only the target string
+func (r *Route) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*r = Route{Target: value.Value}
+		return nil
+	}
+
+	type route Route
+	return value.Decode((*route)(r))
+}
+
+// UnmarshalTOML allows a Route to be configured with only the target string
+func (r *Route) UnmarshalTOML(data any) error {
+	switch v := data.(type) {
+	case string:
+		*r = Route{Target: v}
+		return nil
+	case map[string]any:
+		// re-encoding is the simplest way to use the struct tags with the decoded table
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		type route Route
+		return json.Unmarshal(encoded, (*route)(r))
+	default:
+		return fmt.Errorf("unexpected type for route: %T", data)
+	}
+}
+
+// host returns the hostname or IP from the Target, which might be a URL or include a port
+func (r Route) host() string {
+	u, err := url.Parse(r.Target)
+	if err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+
+	host, _, err := net.SplitHostPort(r.Target)
+	if err == nil {
+		return host
+	}
+
+	return r.Target
+}
+
+// Validate checks the config for mistakes before it is used
+func (c FallbackConfig) Validate() error {
+	for subdomain, route := range c.Routes {
+		_, err := NormalizeSubdomain(subdomain)
+		if err != nil {
+			return fmt.Errorf("invalid route: %w", err)
+		}
+		if route.host() == "" {
+			return fmt.Errorf("route %q has empty target", subdomain)
+		}
+		if route.TTL < 0 {
+			return fmt.Errorf("route %q has negative ttl", subdomain)
+		}
+		if route.Proxy != nil && route.Proxy.Resolver != "" {
+			_, _, err := net.SplitHostPort(route.Proxy.Resolver)
+			if err != nil {
+				return fmt.Errorf("route %q has invalid resolver: %w", subdomain, err)
+			}
+		}
+	}
+
+	reserved := map[string]string{}
+	for subdomain, ip := range c.Reservations {
+		_, err := NormalizeSubdomain(subdomain)
+		if err != nil {
+			return fmt.Errorf("invalid reservation: %w", err)
+		}
+		if ipToBytes(ip) == nil {
+			return fmt.Errorf("reservation %q has invalid IPv4 address: %q", subdomain, ip)
+		}
+		if other, ok := reserved[ip]; ok {
+			return fmt.Errorf("reservations %q and %q use the same IP: %s", other, subdomain, ip)
+		}
+		reserved[ip] = subdomain
+	}
+
+	return nil
+}
+
+// normalized returns a copy of the config with normalized subdomains. An error is returned if a subdomain is
+// invalid or if two subdomains are the same after normalizing
+func (c FallbackConfig) normalized() (FallbackConfig, error) {
+	result := FallbackConfig{}
+
+	if c.Routes != nil {
+		result.Routes = FallbackRoutes{}
+		for subdomain, route := range c.Routes {
+			normalized, err := NormalizeSubdomain(subdomain)
+			if err != nil {
+				return FallbackConfig{}, fmt.Errorf("invalid route: %w", err)
+			}
+			if _, ok := result.Routes[normalized]; ok {
+				return FallbackConfig{}, fmt.Errorf("multiple routes for subdomain %q", normalized)
+			}
+			result.Routes[normalized] = route
+		}
+	}
+
+	if c.Reservations != nil {
+		result.Reservations = map[string]string{}
+		for subdomain, ip := range c.Reservations {
+			normalized, err := NormalizeSubdomain(subdomain)
+			if err != nil {
+				return FallbackConfig{}, fmt.Errorf("invalid reservation: %w", err)
+			}
+			if _, ok := result.Reservations[normalized]; ok {
+				return FallbackConfig{}, fmt.Errorf("multiple reservations for subdomain %q", normalized)
+			}
+			result.Reservations[normalized] = ip
+		}
+	}
+
+	return result, nil
+}
+
+// LoadFallbackConfig reads and validates a JSON, YAML, or TOML config file
+func LoadFallbackConfig(fname string) (FallbackConfig, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return FallbackConfig{}, fmt.Errorf("error reading file: %w", err)
+	}
+
+	var unmarshal func([]byte, any) error
+	switch filepath.Ext(fname) {
+	case ".json":
+		unmarshal = json.Unmarshal
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	case ".toml":
+		unmarshal = toml.Unmarshal
+	default:
+		return FallbackConfig{}, fmt.Errorf("unsupported file type: %q", filepath.Ext(fname))
+	}
+
+	var raw map[string]any
+	err = unmarshal(data, &raw)
+	if err != nil {
+		return FallbackConfig{}, fmt.Errorf("error parsing file: %w", err)
+	}
+
+	var cfg FallbackConfig
+	if isStructuredConfig(raw) {
+		err = unmarshal(data, &cfg)
+	} else {
+		err = unmarshal(data, &cfg.Routes)
+	}
+	if err != nil {
+		return FallbackConfig{}, fmt.Errorf("error parsing file: %w", err)
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return FallbackConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// isStructuredConfig detects the difference between a FallbackConfig and the original flat
+// mapping of subdomain to target, which only used string values
+func isStructuredConfig(raw map[string]any) bool {
+	for _, key := range []string{"routes", "reservations"} {
+		if _, ok := raw[key].(map[string]any); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchFallbackConfig polls the config file and swaps in the new config when it changes. If the
+// new config is invalid, the error is logged and the previous config is kept
+func (m Manager) WatchFallbackConfig(ctx context.Context, fname string, interval time.Duration) error {
+	logger := m.logger.With("file", fname)
+
+	return watch.Poll(ctx, fname, interval, func() {
+		cfg, err := LoadFallbackConfig(fname)
+		if err == nil {
+			err = m.SetFallbackConfig(cfg)
+		}
+		if err != nil {
+			logger.Error("error reloading fallback config, keeping previous config", "error", err)
+			return
+		}
+
+		logger.Info("reloaded fallback config", "routes", len(cfg.Routes), "reservations", len(cfg.Reservations))
+	})
+}
@@ -0,0 +1,243 @@
+package dns
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+func TestLoadFallbackConfig(t *testing.T) {
+	flat := FallbackConfig{Routes: FallbackRoutes{
+		"app": {Target: "https://app.example.com"},
+		"db":  {Target: "10.1.2.3:5432"},
+	}}
+
+	structured := FallbackConfig{
+		Routes: FallbackRoutes{
+			"app": {Target: "https://app.example.com"},
+			"api": {
+				Target: "api.internal",
+				TTL:    Duration(30 * time.Second),
+				Proxy:  &ProxyOptions{Resolver: "10.8.0.1:53", Timeout: Duration(2 * time.Second)},
+			},
+		},
+		Reservations: map[string]string{"app": "10.0.0.4"},
+	}
+
+	tests := []struct {
+		name      string
+		fname     string
+		contents  string
+		expected  FallbackConfig
+		expectErr bool
+	}{
+		{
+			"FlatJSON",
+			"routes.json",
+			`{"app": "https://app.example.com", "db": "10.1.2.3:5432"}`,
+			flat,
+			false,
+		},
+		{
+			"FlatYAML",
+			"routes.yaml",
+			"app: https://app.example.com\ndb: 10.1.2.3:5432\n",
+			flat,
+			false,
+		},
+		{
+			"FlatYML",
+			"routes.yml",
+			"app: https://app.example.com\ndb: 10.1.2.3:5432\n",
+			flat,
+			false,
+		},
+		{
+			"FlatTOML",
+			"routes.toml",
+			"app = \"https://app.example.com\"\ndb = \"10.1.2.3:5432\"\n",
+			flat,
+			false,
+		},
+		{
+			"FlatRouteNamedRoutes",
+			"routes.json",
+			`{"routes": "https://routes.example.com"}`,
+			FallbackConfig{Routes: FallbackRoutes{"routes": {Target: "https://routes.example.com"}}},
+			false,
+		},
+		{
+			"StructuredJSON",
+			"routes.json",
+			`{
+				"routes": {
+					"app": "https://app.example.com",
+					"api": {"target": "api.internal", "ttl": "30s", "proxy": {"resolver": "10.8.0.1:53", "timeout": "2s"}}
+				},
+				"reservations": {"app": "10.0.0.4"}
+			}`,
+			structured,
+			false,
+		},
+		{
+			"StructuredYAML",
+			"routes.yaml",
+			`routes:
+  app: https://app.example.com
+  api:
+    target: api.internal
+    ttl: 30s
+    proxy:
+      resolver: 10.8.0.1:53
+      timeout: 2s
+reservations:
+  app: 10.0.0.4
+`,
+			structured,
+			false,
+		},
+		{
+			"StructuredTOML",
+			"routes.toml",
+			`[routes]
+app = "https://app.example.com"
+
+[routes.api]
+target = "api.internal"
+ttl = "30s"
+proxy = { resolver = "10.8.0.1:53", timeout = "2s" }
+
+[reservations]
+app = "10.0.0.4"
+`,
+			structured,
+			false,
+		},
+		{
+			"OnlyReservations",
+			"routes.json",
+			`{"reservations": {"app": "10.0.0.4"}}`,
+			FallbackConfig{Reservations: map[string]string{"app": "10.0.0.4"}},
+			false,
+		},
+		{
+			"InvalidDurationJSON",
+			"routes.json",
+			`{"routes": {"api": {"target": "api.internal", "ttl": "30 seconds"}}}`,
+			FallbackConfig{},
+			true,
+		},
+		{
+			"InvalidDurationYAML",
+			"routes.yaml",
+			"routes:\n  api:\n    target: api.internal\n    ttl: 30\n",
+			FallbackConfig{},
+			true,
+		},
+		{
+			"InvalidDurationTOML",
+			"routes.toml",
+			"[routes.api]\ntarget = \"api.internal\"\nttl = \"soon\"\n",
+			FallbackConfig{},
+			true,
+		},
+		{
+			"InvalidProxyTimeout",
+			"routes.json",
+			`{"routes": {"api": {"target": "api.internal", "proxy": {"timeout": "1x"}}}}`,
+			FallbackConfig{},
+			true,
+		},
+		{
+			"NegativeTTL",
+			"routes.json",
+			`{"routes": {"api": {"target": "api.internal", "ttl": "-1s"}}}`,
+			FallbackConfig{},
+			true,
+		},
+		{
+			"InvalidResolver",
+			"routes.json",
+			`{"routes": {"api": {"target": "api.internal", "proxy": {"resolver": "10.8.0.1"}}}}`,
+			FallbackConfig{},
+			true,
+		},
+		{
+			"EmptyTarget",
+			"routes.yaml",
+			"app: \"\"\n",
+			FallbackConfig{},
+			true,
+		},
+		{
+			"InvalidSubdomain",
+			"routes.json",
+			`{"my_app": "https://app.example.com"}`,
+			FallbackConfig{},
+			true,
+		},
+		{
+			"InvalidReservation",
+			"routes.json",
+			`{"reservations": {"app": "not-an-ip"}}`,
+			FallbackConfig{},
+			true,
+		},
+		{
+			"DuplicateReservation",
+			"routes.json",
+			`{"reservations": {"app": "10.0.0.4", "api": "10.0.0.4"}}`,
+			FallbackConfig{},
+			true,
+		},
+		{
+			"InvalidJSON",
+			"routes.json",
+			`{"app": `,
+			FallbackConfig{},
+			true,
+		},
+		{
+			"UnsupportedExtension",
+			"routes.ini",
+			"app=https://app.example.com",
+			FallbackConfig{},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fname := filepath.Join(t.TempDir(), tt.fname)
+			err := os.WriteFile(fname, []byte(tt.contents), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := LoadFallbackConfig(fname)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(cfg, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, cfg)
+			}
+		})
+	}
+}
+
+func TestLoadFallbackConfigMissingFile(t *testing.T) {
+	_, err := LoadFallbackConfig(filepath.Join(t.TempDir(), "routes.json"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
@@ -1,47 +1,166 @@
 package dns
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"sync"
+	"time"
 )
 
-// FallbackRoutes maps a subdomain to an actual domain name that should be used
-// when the local subdomain is not running on the server
-type FallbackRoutes map[string]string
+// FallbackRoutes maps a subdomain to a Route that should be used when the local subdomain is not
+// running on the server
+type FallbackRoutes map[string]Route
+
+// fallbackStore holds routes from the config file separately from routes registered at runtime so
+// the config can be reloaded without losing registrations. Registered routes take priority. Internal routes are
+// used by Goblin itself, so they can't be changed with the API and are only used if there is no other route
+type fallbackStore struct {
+	mu         sync.RWMutex
+	config     FallbackConfig
+	registered map[string]registeredRoute
+	internal   map[string]Route
+}
+
+// registeredRoute is a route registered at runtime, which is removed after expiresAt if it is set
+type registeredRoute struct {
+	Route
+	expiresAt *time.Time
+}
+
+func (s *fallbackStore) route(subdomain string) (Route, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	registered, ok := s.registered[subdomain]
+	if ok {
+		return registered.Route, true
+	}
+
+	route, ok := s.config.Routes[subdomain]
+	if ok {
+		return route, true
+	}
+
+	route, ok = s.internal[subdomain]
+	return route, ok
+}
+
+func (s *fallbackStore) reservation(subdomain string) net.IP {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return ipToBytes(s.config.Reservations[subdomain])
+}
+
+// isReservedForOther returns true if the IP is reserved for a different subdomain
+func (s *fallbackStore) isReservedForOther(ip net.IP, subdomain string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for reservedFor, reservedIP := range s.config.Reservations {
+		if reservedFor != subdomain && reservedIP == ip.String() {
+			return true
+		}
+	}
+	return false
+}
 
 func (m Manager) handleFallbackRoutes(subdomain string) (*record, error) {
-	fallback, ok := m.FallbackRoutes[subdomain]
+	route, ok := m.fallbacks.route(subdomain)
 	if !ok {
 		return nil, nil
 	}
 
 	logger := m.logger.With(
 		"subdomain", subdomain,
-		"fallback", fallback,
+		"fallback", route.Target,
 	)
 
 	logger.Debug("found fallback configuration")
 
-	rec := &record{
-		subdomain: "Remote Address (no subdomain)",
+	start := time.Now()
+	ip, err := resolveRoute(context.Background(), route)
+	m.metrics.observeFallbackLookup(err, start)
+	if err != nil {
+		return nil, err
 	}
 
-	fallbackIP, ok := asIP(fallback)
+	logger.With("remote_ip", ip.String()).Debug("found IP address for fallback route")
+
+	return &record{
+		ip:        ip,
+		subdomain: "Remote Address (no subdomain)",
+		ttl:       time.Duration(route.TTL),
+	}, nil
+}
+
+// resolveRoute gets the IP for the route's target
+func resolveRoute(ctx context.Context, route Route) (net.IP, error) {
+	fallbackIP, ok := asIP(route.host())
 	if ok {
-		rec.ip = fallbackIP
-		return rec, nil
+		return fallbackIP, nil
 	}
 
-	var err error
-	rec.ip, err = lookupIP(fallback)
+	ip, err := lookupIP(ctx, route.host(), route.Proxy)
 	if err != nil {
 		return nil, fmt.Errorf("error finding IP for remote address: %w", err)
 	}
 
-	m.logger.With("remote_ip", rec.ip.String()).Debug("found IP address for fallback route")
+	return ip, nil
+}
+
+// routeCache keeps the IPs that routes resolved to for the route's TTL, so showing the status doesn't look up
+// every target each time
+type routeCache struct {
+	mu      sync.Mutex
+	entries map[routeCacheKey]routeCacheEntry
+}
+
+// routeCacheKey identifies a lookup, since the same target can resolve differently with another resolver
+type routeCacheKey struct {
+	host     string
+	resolver string
+}
+
+type routeCacheEntry struct {
+	ip        net.IP
+	expiresAt time.Time
+}
 
-	return rec, nil
+func newRouteCache() *routeCache {
+	return &routeCache{entries: map[routeCacheKey]routeCacheEntry{}}
+}
+
+// resolve uses the cached IP for the route if it hasn't expired, or resolves it and caches the IP for the route's
+// TTL. Errors aren't cached so the next request tries again
+func (c *routeCache) resolve(ctx context.Context, route Route) (net.IP, error) {
+	key := routeCacheKey{host: route.host()}
+	if route.Proxy != nil {
+		key.resolver = route.Proxy.Resolver
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && now.Before(entry.expiresAt) {
+		c.mu.Unlock()
+		return entry.ip, nil
+	}
+	delete(c.entries, key)
+	c.mu.Unlock()
+
+	ip, err := resolveRoute(ctx, route)
+	if err != nil || route.TTL <= 0 {
+		return ip, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = routeCacheEntry{ip: ip, expiresAt: now.Add(time.Duration(route.TTL))}
+	c.mu.Unlock()
+
+	return ip, nil
 }
 
 func asIP(fallback string) (net.IP, bool) {
@@ -52,8 +171,28 @@ func asIP(fallback string) (net.IP, bool) {
 	return ip.To4(), true
 }
 
-func lookupIP(domain string) (net.IP, error) {
-	ips, err := net.LookupIP(domain)
+func lookupIP(ctx context.Context, domain string, opts *ProxyOptions) (net.IP, error) {
+	resolver := net.DefaultResolver
+
+	if opts != nil {
+		if opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(opts.Timeout))
+			defer cancel()
+		}
+
+		if opts.Resolver != "" {
+			resolver = &net.Resolver{
+				PreferGo: true,
+				Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, network, opts.Resolver)
+				},
+			}
+		}
+	}
+
+	ips, err := resolver.LookupIP(ctx, "ip4", domain)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +207,108 @@ func lookupIP(domain string) (net.IP, error) {
 	return nil, errors.New("no ip found for domain")
 }
 
-// RegisterFallback allows registering a fallback domain that will be used if a Goblin plugin is not running
-func (m Manager) RegisterFallback(subdomain, address string) {
-	m.FallbackRoutes[subdomain] = address
+// RegisterFallback allows registering a fallback domain that will be used if a Goblin plugin is not running.
+// If expiresIn is not zero, the route is automatically removed after that duration
+func (m Manager) RegisterFallback(subdomain, address string, expiresIn time.Duration) error {
+	subdomain, err := NormalizeSubdomain(subdomain)
+	if err != nil {
+		return err
+	}
+
+	m.fallbacks.mu.Lock()
+	defer m.fallbacks.mu.Unlock()
+
+	if _, ok := m.fallbacks.internal[subdomain]; ok {
+		return fmt.Errorf("%w: %q is used by Goblin", ErrReservedSubdomain, subdomain)
+	}
+
+	registered := registeredRoute{Route: Route{Target: address}}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		registered.expiresAt = &expiresAt
+		time.AfterFunc(expiresIn, func() {
+			m.expireFallback(subdomain, expiresAt)
+		})
+	}
+
+	m.fallbacks.registered[subdomain] = registered
+	m.events.publish(Event{Type: EventFallbackRegistered, Subdomain: subdomain, Target: address})
+
+	return nil
+}
+
+// RegisterInternalFallback adds a route that Goblin uses itself, like for the dashboard. It is only used if the
+// subdomain doesn't have another route, and it can't be registered or removed with the API
+func (m Manager) RegisterInternalFallback(subdomain, address string) error {
+	subdomain, err := NormalizeSubdomain(subdomain)
+	if err != nil {
+		return err
+	}
+
+	m.fallbacks.mu.Lock()
+	defer m.fallbacks.mu.Unlock()
+
+	m.fallbacks.internal[subdomain] = Route{Target: address}
+	m.events.publish(Event{Type: EventFallbackRegistered, Subdomain: subdomain, Target: address})
+
+	return nil
+}
+
+// expireFallback removes a registered route if it has not been replaced since it was scheduled to expire
+func (m Manager) expireFallback(subdomain string, expiresAt time.Time) {
+	m.fallbacks.mu.Lock()
+	defer m.fallbacks.mu.Unlock()
+
+	registered, ok := m.fallbacks.registered[subdomain]
+	if !ok || registered.expiresAt == nil || !registered.expiresAt.Equal(expiresAt) {
+		return
+	}
+
+	delete(m.fallbacks.registered, subdomain)
+	m.logger.Info("removed expired fallback route", "subdomain", subdomain, "fallback", registered.Target)
+	m.events.publish(Event{Type: EventFallbackRemoved, Subdomain: subdomain, Target: registered.Target})
+}
+
+// SetFallbackConfig validates and replaces the routes and reservations from the config file. Routes
+// registered at runtime are not changed
+func (m Manager) SetFallbackConfig(cfg FallbackConfig) error {
+	cfg, err := cfg.normalized()
+	if err != nil {
+		return err
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	for subdomain, ip := range cfg.Reservations {
+		if !m.subnet.Contains(ipToBytes(ip)) {
+			return fmt.Errorf("reservation %q has IP outside of subnet %s: %s", subdomain, m.subnet, ip)
+		}
+	}
+
+	if cfg.Routes == nil {
+		cfg.Routes = FallbackRoutes{}
+	}
+
+	m.fallbacks.mu.Lock()
+	defer m.fallbacks.mu.Unlock()
+
+	previous := m.fallbacks.config.Routes
+	m.fallbacks.config = cfg
+
+	for subdomain, route := range cfg.Routes {
+		prev, ok := previous[subdomain]
+		if !ok || prev.Target != route.Target {
+			m.events.publish(Event{Type: EventFallbackRegistered, Subdomain: subdomain, Target: route.Target})
+		}
+	}
+	for subdomain, route := range previous {
+		if _, ok := cfg.Routes[subdomain]; !ok {
+			m.events.publish(Event{Type: EventFallbackRemoved, Subdomain: subdomain, Target: route.Target})
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,138 @@
+package dns
+
+import (
+	"testing"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+func TestInternalFallback(t *testing.T) {
+	m := newTestManager(t)
+
+	err := m.RegisterInternalFallback("goblin", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("CantRegister", func(t *testing.T) {
+		err := m.RegisterFallback("goblin", "192.168.1.10", 0)
+		if !errors.Is(err, ErrReservedSubdomain) {
+			t.Errorf("expected ErrReservedSubdomain, got %v", err)
+		}
+	})
+
+	t.Run("CantRemove", func(t *testing.T) {
+		err := m.RemoveFallback("goblin")
+		if !errors.Is(err, ErrReservedSubdomain) {
+			t.Errorf("expected ErrReservedSubdomain, got %v", err)
+		}
+
+		fallback, err := m.Fallback("goblin")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fallback.Source != FallbackSourceInternal || fallback.Route.Target != "127.0.0.1" {
+			t.Errorf("unexpected fallback: %+v", fallback)
+		}
+	})
+
+	t.Run("ConfigRouteIsUsedFirst", func(t *testing.T) {
+		err := m.SetFallbackConfig(FallbackConfig{Routes: FallbackRoutes{"goblin": {Target: "192.168.1.10"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lookup, err := m.Lookup("goblin")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if lookup.IP != "192.168.1.10" {
+			t.Errorf("expected config route's IP, got %s", lookup.IP)
+		}
+
+		fallbacks := m.Fallbacks()
+		if len(fallbacks) != 1 || fallbacks[0].Source != FallbackSourceConfig {
+			t.Errorf("expected only the config route, got %+v", fallbacks)
+		}
+
+		// removing the config route makes the internal one available again
+		err = m.SetFallbackConfig(FallbackConfig{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		lookup, err = m.Lookup("goblin")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if lookup.IP != "127.0.0.1" {
+			t.Errorf("expected internal route's IP, got %s", lookup.IP)
+		}
+	})
+}
+
+func TestRemoveFallback(t *testing.T) {
+	cfg := FallbackConfig{Routes: FallbackRoutes{
+		"config":     {Target: "192.168.1.10"},
+		"overridden": {Target: "192.168.1.11"},
+	}}
+
+	tests := []struct {
+		name          string
+		subdomain     string
+		expectedError error
+		// expectedTarget is the route's target after it is removed and the config is reloaded, or "" if there
+		// shouldn't be a route
+		expectedTarget string
+	}{
+		{"Registered", "registered", nil, ""},
+		{"ConfigRoute", "config", ErrConfiguredRoute, "192.168.1.10"},
+		{"RegisteredOverConfigRoute", "overridden", nil, "192.168.1.11"},
+		{"Missing", "missing", ErrNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t)
+
+			err := m.SetFallbackConfig(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, subdomain := range []string{"registered", "overridden"} {
+				err = m.RegisterFallback(subdomain, "10.1.1.1", 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = m.RemoveFallback(tt.subdomain)
+			if tt.expectedError == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectedError != nil && !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected %v, got %v", tt.expectedError, err)
+			}
+
+			// the config is reloaded when the file changes
+			err = m.SetFallbackConfig(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fallback, err := m.Fallback(tt.subdomain)
+			if tt.expectedTarget == "" {
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("expected no route, got %+v and error %v", fallback, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fallback.Source != FallbackSourceConfig || fallback.Route.Target != tt.expectedTarget {
+				t.Errorf("expected config route to %s, got %+v", tt.expectedTarget, fallback)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/calvinmclean/goblin/errors"
@@ -20,9 +21,14 @@ port %s`
 )
 
 type record struct {
-	ip        net.IP
-	subdomain string
-	removedAt *time.Time
+	ip          net.IP
+	subdomain   string
+	allocatedAt time.Time
+	removedAt   *time.Time
+	// ttl is used in DNS responses. It is only set for fallback routes
+	ttl time.Duration
+	// pending records are allocated, but DNS uses the fallback route until they are ready
+	pending bool
 }
 
 func (r *record) isActive() bool {
@@ -33,9 +39,17 @@ type Manager struct {
 	Config
 
 	// allocatedIPs and subdomains point to the same data but with IP or Subdomain as the key
+	// mu protects both maps and the records in them
+	mu           *sync.RWMutex
 	allocatedIPs map[string]*record
 	subdomains   map[string]*record
 
+	fallbacks  *fallbackStore
+	routeCache *routeCache
+	events     *eventBus
+	metrics    *managerMetrics
+	queries    *queryLog
+
 	subnet *net.IPNet
 	logger *slog.Logger
 }
@@ -44,38 +58,83 @@ type Config struct {
 	Address        string
 	Domain         string
 	FallbackRoutes FallbackRoutes
+	// Reservations map a subdomain to the IP it should always be allocated
+	Reservations map[string]string
+
+	// QueryLogSize is the number of recent DNS queries kept in memory. The default is DefaultQueryLogSize
+	QueryLogSize int
+	// QueryLogFile is an optional path to a JSONL file where all DNS queries are appended
+	QueryLogFile string
+	// QueryLogFileBytes is the size of the QueryLogFile before it is rotated. The default is DefaultQueryLogFileBytes
+	QueryLogFileBytes int64
+
+	// Logger is used for all logs from the Manager. The default is slog.Default()
+	Logger *slog.Logger
 }
 
+// New creates a Manager and checks that the system's resolver file and IP aliases are set up
 func New(cfg Config) (Manager, error) {
+	manager, err := NewUnchecked(cfg)
+	if err != nil {
+		return Manager{}, err
+	}
+
+	err = checkResolverFile(manager.Domain, cfg.Address)
+	if err != nil {
+		return Manager{}, err
+	}
+
+	numIPs, err := manager.checkIPAliases()
+	if err != nil {
+		return Manager{}, err
+	}
+
+	manager.logger.Info("found IP aliases", "count", numIPs)
+
+	return manager, nil
+}
+
+// NewUnchecked creates a Manager without checking the system's resolver file and IP aliases, so it can be used
+// in tests. IPs can't be allocated if the aliases don't exist
+func NewUnchecked(cfg Config) (Manager, error) {
 	_, subnet, err := net.ParseCIDR(defaultSubnet)
 	if err != nil {
 		return Manager{}, fmt.Errorf("error parsing subnet: %w", err)
 	}
 
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	cfg.Domain = strings.ToLower(strings.Trim(cfg.Domain, "."))
+
 	manager := Manager{
 		Config:       cfg,
+		mu:           &sync.RWMutex{},
 		allocatedIPs: map[string]*record{},
 		subdomains:   map[string]*record{},
+		fallbacks:    &fallbackStore{registered: map[string]registeredRoute{}, internal: map[string]Route{}},
+		events:       newEventBus(),
+		routeCache:   newRouteCache(),
 		subnet:       subnet,
-		logger:       slog.Default(),
-	}
-
-	if manager.Config.FallbackRoutes == nil {
-		manager.Config.FallbackRoutes = map[string]string{}
+		logger:       logger.With("component", "dns"),
 	}
+	manager.metrics = newManagerMetrics(manager)
 
-	err = checkResolverFile(cfg.Domain, cfg.Address)
+	manager.queries, err = newQueryLog(cfg.QueryLogSize, cfg.QueryLogFile, cfg.QueryLogFileBytes)
 	if err != nil {
 		return Manager{}, err
 	}
 
-	numIPs, err := manager.checkIPAliases()
+	err = manager.SetFallbackConfig(FallbackConfig{
+		Routes:       cfg.FallbackRoutes,
+		Reservations: cfg.Reservations,
+	})
 	if err != nil {
-		return Manager{}, err
+		return Manager{}, fmt.Errorf("invalid fallback config: %w", err)
 	}
 
-	manager.logger.Info("found IP aliases", "count", numIPs)
-
 	return manager, nil
 }
 
@@ -172,10 +231,15 @@ func (m Manager) getExistingRecord(subdomain string) (*record, error) {
 		return nil, ErrSubdomainInUse
 	}
 
+	// the IP from the previous allocation has been given to a different subdomain
+	if m.allocatedIPs[rec.ip.String()] != rec {
+		return nil, nil
+	}
+
 	return rec, nil
 }
 
-func (m Manager) getNextAvailableIP() (net.IP, []net.IP, error) {
+func (m Manager) getNextAvailableIP(subdomain string) (net.IP, []net.IP, error) {
 	ipIter, err := m.getIPs()
 	if err != nil {
 		return nil, nil, fmt.Errorf("error getting IPs from system: %w", err)
@@ -183,6 +247,11 @@ func (m Manager) getNextAvailableIP() (net.IP, []net.IP, error) {
 
 	unallocatedIPs := []net.IP{}
 	for ip := range ipIter {
+		// skip IPs that are saved for other subdomains
+		if m.fallbacks.isReservedForOther(ip, subdomain) {
+			continue
+		}
+
 		rec := m.allocatedIPs[ip.String()]
 		// IP is not currently in-use so it can be used
 		if rec == nil {
@@ -208,31 +277,117 @@ func (m Manager) findOrCreateRecord(subdomain string) (*record, error) {
 		return rec, nil
 	}
 
-	ip, unallocatedIPs, err := m.getNextAvailableIP()
+	rec = m.findReservedRecord(subdomain)
+	if rec != nil {
+		return rec, nil
+	}
+
+	ip, unallocatedIPs, err := m.getNextAvailableIP(subdomain)
 	if err != nil {
 		return nil, err
 	}
 	if ip != nil {
-		return &record{ip, subdomain, nil}, nil
+		return &record{ip: ip, subdomain: subdomain}, nil
 	}
 
 	// if all unallocated IPs are exhausted, use the oldest removed IP
 	rec = m.findOldestDeallocatedIP(unallocatedIPs)
 	if rec != nil {
-		return rec, nil
+		return &record{ip: rec.ip, subdomain: subdomain}, nil
 	}
 
 	return nil, ErrNoAvailableIPs
 }
 
+// findReservedRecord creates a record with the subdomain's reserved IP if it is configured and
+// not actively used by another subdomain
+func (m Manager) findReservedRecord(subdomain string) *record {
+	ip := m.fallbacks.reservation(subdomain)
+	if ip == nil {
+		return nil
+	}
+
+	existing := m.allocatedIPs[ip.String()]
+	if existing != nil && existing.isActive() {
+		m.logger.Warn("reserved IP is in-use by another subdomain", "ip", ip, "subdomain", subdomain, "used_by", existing.subdomain)
+		return nil
+	}
+
+	return &record{ip: ip, subdomain: subdomain}
+}
+
 // GetIP allocates and returns an IP address. It will keep it open until the context is closed
 func (m Manager) GetIP(ctx context.Context, subdomain string) (string, error) {
+	return m.allocate(ctx, subdomain, false)
+}
+
+// GetPendingIP allocates an IP like GetIP, but DNS keeps using the subdomain's fallback route until SetReady
+// is called. This way, clients don't reach the local instance before it is ready
+func (m Manager) GetPendingIP(ctx context.Context, subdomain string) (string, error) {
+	return m.allocate(ctx, subdomain, true)
+}
+
+// SetReady switches DNS for the subdomain from its fallback route to its pending IP allocation
+func (m Manager) SetReady(_ context.Context, subdomain string) error {
+	return m.setPending(subdomain, false)
+}
+
+// SetPending switches DNS for the subdomain back to its fallback route while it keeps the IP allocation, like
+// when the local instance is restarting. SetReady switches it back
+func (m Manager) SetPending(_ context.Context, subdomain string) error {
+	return m.setPending(subdomain, true)
+}
+
+func (m Manager) setPending(subdomain string, pending bool) error {
+	subdomain, err := NormalizeSubdomain(subdomain)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	rec, ok := m.subdomains[subdomain]
+	if !ok || !rec.isActive() {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: active record for subdomain %q", ErrNotFound, subdomain)
+	}
+	changed := rec.pending != pending
+	rec.pending = pending
+	ip := rec.ip.String()
+	m.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+
+	eventType := EventReady
+	if pending {
+		eventType = EventPending
+	}
+	m.logger.Debug("changed IP readiness", "ip", ip, "subdomain", subdomain, "pending", pending)
+	m.events.publish(Event{Type: eventType, Subdomain: subdomain, IP: ip})
+
+	return nil
+}
+
+func (m Manager) allocate(ctx context.Context, subdomain string, pending bool) (string, error) {
+	subdomain, err := NormalizeSubdomain(subdomain)
+	if err != nil {
+		m.metrics.observeAllocationError(err)
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	rec, err := m.findOrCreateRecord(subdomain)
 	if err != nil {
+		m.metrics.observeAllocationError(err)
 		return "", err
 	}
 
+	rec.pending = pending
 	m.allocateIPRecord(ctx, rec)
+	m.metrics.allocations.Inc()
 	return rec.ip.String(), nil
 }
 
@@ -260,18 +415,27 @@ func (m Manager) findOldestDeallocatedIP(unallocatedIPs []net.IP) *record {
 }
 
 func (m Manager) allocateIPRecord(ctx context.Context, rec *record) {
+	rec.allocatedAt = time.Now()
 	rec.removedAt = nil
 	m.allocatedIPs[rec.ip.String()] = rec
 	m.subdomains[rec.subdomain] = rec
 	go m.removeIP(ctx, rec)
 
 	m.logger.Debug("allocated IP", "ip", rec.ip, "subdomain", rec.subdomain)
+	m.events.publish(Event{Type: EventAllocated, Subdomain: rec.subdomain, IP: rec.ip.String()})
 }
 
 func (m Manager) removeIP(ctx context.Context, rec *record) {
 	<-ctx.Done()
 	now := time.Now()
+
+	m.mu.Lock()
 	rec.removedAt = &now
+	m.mu.Unlock()
+
+	m.metrics.releases.Inc()
+	m.metrics.allocationLifetimes.Observe(now.Sub(rec.allocatedAt).Seconds())
 
-	m.logger.Debug("removed IP", "ip", rec.ip)
+	m.logger.Debug("removed IP", "ip", rec.ip, "subdomain", rec.subdomain)
+	m.events.publish(Event{Type: EventReleased, Time: now, Subdomain: rec.subdomain, IP: rec.ip.String()})
 }
@@ -0,0 +1,115 @@
+package dns
+
+import (
+	"errors"
+	"time"
+
+	"github.com/calvinmclean/goblin/metrics"
+)
+
+// values for the result label of DNS query metrics
+const (
+	queryResultLocal    = "local"
+	queryResultFallback = "fallback"
+	queryResultMiss     = "miss"
+	queryResultIgnored  = "ignored"
+	queryResultError    = "error"
+)
+
+type managerMetrics struct {
+	registry *metrics.Registry
+
+	queries             *metrics.Counter
+	queryDuration       *metrics.Histogram
+	fallbackLookup      *metrics.Histogram
+	allocations         *metrics.Counter
+	allocationErrors    *metrics.Counter
+	releases            *metrics.Counter
+	allocationLifetimes *metrics.Histogram
+}
+
+func newManagerMetrics(m Manager) *managerMetrics {
+	registry := metrics.NewRegistry()
+
+	mm := &managerMetrics{
+		registry: registry,
+		queries: registry.Counter(
+			"goblin_dns_queries_total",
+			"DNS queries handled by result: local, fallback, miss, ignored, or error",
+			"result",
+		),
+		queryDuration: registry.Histogram(
+			"goblin_dns_query_duration_seconds",
+			"Time to handle a DNS query",
+			nil, "result",
+		),
+		fallbackLookup: registry.Histogram(
+			"goblin_fallback_lookup_duration_seconds",
+			"Time to resolve a fallback route's target",
+			nil, "result",
+		),
+		allocations: registry.Counter(
+			"goblin_ip_allocations_total",
+			"IPs allocated to subdomains",
+		),
+		allocationErrors: registry.Counter(
+			"goblin_ip_allocation_errors_total",
+			"Failed IP allocations by reason",
+			"reason",
+		),
+		releases: registry.Counter(
+			"goblin_ip_releases_total",
+			"IPs released by subdomains",
+		),
+		allocationLifetimes: registry.Histogram(
+			"goblin_ip_allocation_lifetime_seconds",
+			"Time that an IP was allocated before it was released",
+			[]float64{1, 10, 60, 300, 900, 3600, 4 * 3600, 24 * 3600},
+		),
+	}
+
+	registry.GaugeFunc("goblin_ip_pool", "IPs in the pool by status", "status", func() map[string]float64 {
+		pool, err := m.IPPool()
+		if err != nil {
+			return nil
+		}
+		return map[string]float64{
+			IPStatusActive:    float64(pool.Active),
+			IPStatusReleased:  float64(pool.Released),
+			IPStatusAvailable: float64(pool.Available),
+		}
+	})
+
+	return mm
+}
+
+func (mm *managerMetrics) observeQuery(result string, start time.Time) {
+	mm.queries.Inc(result)
+	mm.queryDuration.Observe(time.Since(start).Seconds(), result)
+}
+
+func (mm *managerMetrics) observeFallbackLookup(err error, start time.Time) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	mm.fallbackLookup.Observe(time.Since(start).Seconds(), result)
+}
+
+func (mm *managerMetrics) observeAllocationError(err error) {
+	reason := "other"
+	switch {
+	case errors.Is(err, ErrSubdomainInUse):
+		reason = "subdomain_in_use"
+	case errors.Is(err, ErrNoAvailableIPs):
+		reason = "no_available_ips"
+	case errors.Is(err, ErrInvalidSubdomain):
+		reason = "invalid_subdomain"
+	}
+	mm.allocationErrors.Inc(reason)
+}
+
+// Metrics returns the registry with the Manager's metrics so they can be served by the API
+func (m Manager) Metrics() *metrics.Registry {
+	return m.metrics.registry
+}
@@ -0,0 +1,205 @@
+package dns
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultQueryLogSize      = 1000
+	DefaultQueryLogFileBytes = 10 * 1024 * 1024
+	queryLogFileBackups      = 3
+)
+
+// QueryLogEntry describes a DNS query handled by the server
+type QueryLogEntry struct {
+	Time      time.Time `json:"time"`
+	Client    string    `json:"client"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Subdomain string    `json:"subdomain,omitempty"`
+	Answer    string    `json:"answer,omitempty"`
+	// Source is local, fallback, miss, ignored, or error
+	Source  string   `json:"source"`
+	Error   string   `json:"error,omitempty"`
+	Latency Duration `json:"latency"`
+}
+
+// QueryFilter limits the entries returned from the query log. Empty fields are not used for filtering
+type QueryFilter struct {
+	Subdomain string
+	Source    string
+	Type      string
+	Since     time.Time
+	// Limit is the maximum number of entries to return, starting with the newest
+	Limit int
+}
+
+func (f QueryFilter) matches(e QueryLogEntry) bool {
+	switch {
+	case f.Subdomain != "" && f.Subdomain != e.Subdomain:
+		return false
+	case f.Source != "" && f.Source != e.Source:
+		return false
+	case f.Type != "" && f.Type != e.Type:
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	}
+	return true
+}
+
+// queryLog keeps a fixed number of recent entries in memory and optionally appends all entries to a file
+type queryLog struct {
+	mu      sync.RWMutex
+	entries []QueryLogEntry
+	next    int
+	full    bool
+
+	file *rotatingFile
+}
+
+func newQueryLog(size int, fname string, maxFileBytes int64) (*queryLog, error) {
+	if size <= 0 {
+		size = DefaultQueryLogSize
+	}
+
+	ql := &queryLog{entries: make([]QueryLogEntry, size)}
+
+	if fname != "" {
+		if maxFileBytes <= 0 {
+			maxFileBytes = DefaultQueryLogFileBytes
+		}
+
+		var err error
+		ql.file, err = openRotatingFile(fname, maxFileBytes, queryLogFileBackups)
+		if err != nil {
+			return nil, fmt.Errorf("error opening query log file: %w", err)
+		}
+	}
+
+	return ql, nil
+}
+
+func (ql *queryLog) add(e QueryLogEntry) error {
+	ql.mu.Lock()
+	defer ql.mu.Unlock()
+
+	ql.entries[ql.next] = e
+	ql.next = (ql.next + 1) % len(ql.entries)
+	if ql.next == 0 {
+		ql.full = true
+	}
+
+	if ql.file == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return ql.file.writeLine(data)
+}
+
+// query returns matching entries, starting with the newest
+func (ql *queryLog) query(f QueryFilter) []QueryLogEntry {
+	ql.mu.RLock()
+	defer ql.mu.RUnlock()
+
+	count := ql.next
+	if ql.full {
+		count = len(ql.entries)
+	}
+
+	result := []QueryLogEntry{}
+	for i := 1; i <= count; i++ {
+		e := ql.entries[(ql.next-i+len(ql.entries))%len(ql.entries)]
+		if !f.matches(e) {
+			continue
+		}
+
+		result = append(result, e)
+		if f.Limit > 0 && len(result) >= f.Limit {
+			break
+		}
+	}
+
+	return result
+}
+
+// Queries returns recent DNS queries from the query log, starting with the newest
+func (m Manager) Queries(f QueryFilter) []QueryLogEntry {
+	return m.queries.query(f)
+}
+
+// rotatingFile appends lines to a file and renames it with a numbered suffix when it reaches the max size
+type rotatingFile struct {
+	fname    string
+	maxBytes int64
+	backups  int
+
+	f    *os.File
+	size int64
+}
+
+func openRotatingFile(fname string, maxBytes int64, backups int) (*rotatingFile, error) {
+	rf := &rotatingFile{fname: fname, maxBytes: maxBytes, backups: backups}
+	return rf, rf.open()
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.fname, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	rf.f = f
+	rf.size = info.Size()
+	return nil
+}
+
+func (rf *rotatingFile) writeLine(data []byte) error {
+	if rf.size+int64(len(data))+1 > rf.maxBytes {
+		err := rf.rotate()
+		if err != nil {
+			return fmt.Errorf("error rotating file: %w", err)
+		}
+	}
+
+	n, err := rf.f.Write(append(data, '\n'))
+	rf.size += int64(n)
+	return err
+}
+
+// rotate renames file.1 to file.2 and so on, removing the oldest, and then starts a new file
+func (rf *rotatingFile) rotate() error {
+	err := rf.f.Close()
+	if err != nil {
+		return err
+	}
+
+	for i := rf.backups - 1; i > 0; i-- {
+		err = os.Rename(fmt.Sprintf("%s.%d", rf.fname, i), fmt.Sprintf("%s.%d", rf.fname, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	err = os.Rename(rf.fname, rf.fname+".1")
+	if err != nil {
+		return err
+	}
+
+	return rf.open()
+}
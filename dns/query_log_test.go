@@ -0,0 +1,212 @@
+package dns
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// entryNames returns the Name of each entry so results are easy to compare
+func entryNames(entries []QueryLogEntry) []string {
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func TestQueryLogWraparound(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		added    int
+		expected []string
+	}{
+		{"Empty", 3, 0, []string{}},
+		{"NotFull", 3, 2, []string{"q1", "q0"}},
+		{"Full", 3, 3, []string{"q2", "q1", "q0"}},
+		{"WrappedOnce", 3, 4, []string{"q3", "q2", "q1"}},
+		{"WrappedToStart", 3, 6, []string{"q5", "q4", "q3"}},
+		{"WrappedTwice", 3, 7, []string{"q6", "q5", "q4"}},
+		{"SizeOne", 1, 5, []string{"q4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ql, err := newQueryLog(tt.size, "", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := range tt.added {
+				err = ql.add(QueryLogEntry{Name: fmt.Sprintf("q%d", i)})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			actual := entryNames(ql.query(QueryFilter{}))
+			if !slices.Equal(actual, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestQueryLogFilter(t *testing.T) {
+	start := time.Now()
+
+	ql, err := newQueryLog(4, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first entry is overwritten, so it is never returned
+	for _, e := range []QueryLogEntry{
+		{Name: "q0", Subdomain: "app", Source: queryResultLocal, Type: "A", Time: start},
+		{Name: "q1", Subdomain: "app", Source: queryResultLocal, Type: "A", Time: start.Add(time.Second)},
+		{Name: "q2", Subdomain: "db", Source: queryResultFallback, Type: "A", Time: start.Add(2 * time.Second)},
+		{Name: "q3", Subdomain: "app", Source: queryResultMiss, Type: "AAAA", Time: start.Add(3 * time.Second)},
+		{Name: "q4", Subdomain: "db", Source: queryResultLocal, Type: "A", Time: start.Add(4 * time.Second)},
+	} {
+		err = ql.add(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		filter   QueryFilter
+		expected []string
+	}{
+		{"All", QueryFilter{}, []string{"q4", "q3", "q2", "q1"}},
+		{"Subdomain", QueryFilter{Subdomain: "app"}, []string{"q3", "q1"}},
+		{"Source", QueryFilter{Source: queryResultLocal}, []string{"q4", "q1"}},
+		{"Type", QueryFilter{Type: "AAAA"}, []string{"q3"}},
+		{"Since", QueryFilter{Since: start.Add(3 * time.Second)}, []string{"q4", "q3"}},
+		{"Limit", QueryFilter{Limit: 2}, []string{"q4", "q3"}},
+		{"LimitAfterFilter", QueryFilter{Subdomain: "db", Limit: 1}, []string{"q4"}},
+		{"NoMatch", QueryFilter{Subdomain: "missing"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := entryNames(ql.query(tt.filter))
+			if !slices.Equal(actual, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	// each line is 10 bytes with the newline, so a file holds 3 lines before it rotates
+	line := func(i int) []byte {
+		return []byte(fmt.Sprintf("line-%04d", i))
+	}
+
+	tests := []struct {
+		name string
+		// existing is written to the file before it is opened
+		existing string
+		lines    int
+		// expected has the lines in the file and each backup, or "" if the file doesn't exist
+		expected []string
+	}{
+		{
+			"UnderLimit",
+			"",
+			2,
+			[]string{"line-0000\nline-0001\n", "", "", "", ""},
+		},
+		{
+			"AtLimit",
+			"",
+			3,
+			[]string{"line-0000\nline-0001\nline-0002\n", "", "", "", ""},
+		},
+		{
+			"Rotated",
+			"",
+			4,
+			[]string{"line-0003\n", "line-0000\nline-0001\nline-0002\n", "", "", ""},
+		},
+		{
+			"ExistingFileSizeIsCounted",
+			"existing\n",
+			3,
+			[]string{"line-0002\n", "existing\nline-0000\nline-0001\n", "", "", ""},
+		},
+		{
+			"MaxBackups",
+			"",
+			12,
+			[]string{
+				"line-0009\nline-0010\nline-0011\n",
+				"line-0006\nline-0007\nline-0008\n",
+				"line-0003\nline-0004\nline-0005\n",
+				"line-0000\nline-0001\nline-0002\n",
+				"",
+			},
+		},
+		{
+			"OldestBackupRemoved",
+			"",
+			13,
+			[]string{
+				"line-0012\n",
+				"line-0009\nline-0010\nline-0011\n",
+				"line-0006\nline-0007\nline-0008\n",
+				"line-0003\nline-0004\nline-0005\n",
+				"",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fname := filepath.Join(t.TempDir(), "queries.log")
+			if tt.existing != "" {
+				err := os.WriteFile(fname, []byte(tt.existing), 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rf, err := openRotatingFile(fname, 30, queryLogFileBackups)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { rf.f.Close() })
+
+			for i := range tt.lines {
+				err = rf.writeLine(line(i))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for i, expected := range tt.expected {
+				name := fname
+				if i > 0 {
+					name = fmt.Sprintf("%s.%d", fname, i)
+				}
+
+				data, err := os.ReadFile(name)
+				if os.IsNotExist(err) && expected == "" {
+					continue
+				}
+				if err != nil {
+					t.Fatalf("error reading %s: %v", filepath.Base(name), err)
+				}
+				if string(data) != expected {
+					t.Errorf("expected %s to have %q, got %q", filepath.Base(name), expected, string(data))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	FallbackSourceConfig     = "config"
+	FallbackSourceRegistered = "registered"
+	FallbackSourceInternal   = "internal"
+
+	IPStatusAvailable = "available"
+	IPStatusActive    = "active"
+	IPStatusReleased  = "released"
+)
+
+// statusLookupTimeout limits how long Status waits for each fallback route's target to resolve
+var statusLookupTimeout = 2 * time.Second
+
+// Record describes a subdomain's IP allocation
+type Record struct {
+	Subdomain string `json:"subdomain"`
+	IP        string `json:"ip"`
+	Active    bool   `json:"active"`
+	// Pending is true until the allocation is ready, and DNS uses the fallback route until then
+	Pending     bool       `json:"pending,omitempty"`
+	AllocatedAt time.Time  `json:"allocated_at"`
+	RemovedAt   *time.Time `json:"removed_at,omitempty"`
+}
+
+// Fallback describes a fallback route and where it came from
+type Fallback struct {
+	Subdomain string `json:"subdomain"`
+	Route     Route  `json:"route"`
+	// Source is FallbackSourceConfig, FallbackSourceRegistered, or FallbackSourceInternal
+	Source string `json:"source"`
+	// ExpiresAt is when a registered route will be removed
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// IPPool describes the usage of IPs available to the server
+type IPPool struct {
+	Subnet    string     `json:"subnet"`
+	Total     int        `json:"total"`
+	Active    int        `json:"active"`
+	Released  int        `json:"released"`
+	Available int        `json:"available"`
+	IPs       []IPStatus `json:"ips"`
+}
+
+// IPStatus describes the usage of a single IP
+type IPStatus struct {
+	IP string `json:"ip"`
+	// Status is IPStatusAvailable, IPStatusActive, or IPStatusReleased
+	Status      string `json:"status"`
+	Subdomain   string `json:"subdomain,omitempty"`
+	ReservedFor string `json:"reserved_for,omitempty"`
+}
+
+func (r *record) toRecord() Record {
+	return Record{
+		Subdomain:   r.subdomain,
+		IP:          r.ip.String(),
+		Active:      r.isActive(),
+		Pending:     r.pending,
+		AllocatedAt: r.allocatedAt,
+		RemovedAt:   r.removedAt,
+	}
+}
+
+func (r registeredRoute) toFallback(subdomain string) Fallback {
+	return Fallback{
+		Subdomain: subdomain,
+		Route:     r.Route,
+		Source:    FallbackSourceRegistered,
+		ExpiresAt: r.expiresAt,
+	}
+}
+
+// Records returns all subdomains that have been allocated an IP, including ones that are no longer active
+func (m Manager) Records() []Record {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]Record, 0, len(m.subdomains))
+	for _, rec := range m.subdomains {
+		result = append(result, rec.toRecord())
+	}
+
+	slices.SortFunc(result, func(a, b Record) int {
+		return strings.Compare(a.Subdomain, b.Subdomain)
+	})
+
+	return result
+}
+
+// Record returns the allocation for a subdomain or ErrNotFound
+func (m Manager) Record(subdomain string) (Record, error) {
+	subdomain, err := NormalizeSubdomain(subdomain)
+	if err != nil {
+		return Record{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rec, ok := m.subdomains[subdomain]
+	if !ok {
+		return Record{}, fmt.Errorf("%w: record for subdomain %q", ErrNotFound, subdomain)
+	}
+
+	return rec.toRecord(), nil
+}
+
+// Lookup is the IP that DNS answers with for a subdomain
+type Lookup struct {
+	Subdomain string `json:"subdomain"`
+	FQDN      string `json:"fqdn"`
+	IP        string `json:"ip"`
+	// Source is "local" for an allocated IP or "fallback" for a fallback route
+	Source string `json:"source"`
+}
+
+// Lookup finds the IP that DNS answers with for a subdomain, so it can be used without a DNS query. It returns
+// ErrNotFound if the subdomain isn't allocated and doesn't have a fallback route
+func (m Manager) Lookup(subdomain string) (Lookup, error) {
+	subdomain, err := NormalizeSubdomain(subdomain)
+	if err != nil {
+		return Lookup{}, err
+	}
+
+	rec, source, err := m.resolve(subdomain)
+	if err != nil {
+		return Lookup{}, err
+	}
+
+	return Lookup{
+		Subdomain: subdomain,
+		FQDN:      subdomain + "." + m.Domain,
+		IP:        rec.ip.String(),
+		Source:    source,
+	}, nil
+}
+
+// Info describes how the server's DNS is configured
+type Info struct {
+	Domain string `json:"domain"`
+}
+
+// Info returns the server's DNS configuration
+func (m Manager) Info() Info {
+	return Info{Domain: m.Domain}
+}
+
+// Fallbacks returns all fallback routes. If a subdomain has more than one route, only the one that is used is
+// returned
+func (m Manager) Fallbacks() []Fallback {
+	m.fallbacks.mu.RLock()
+	defer m.fallbacks.mu.RUnlock()
+
+	result := []Fallback{}
+	for subdomain, registered := range m.fallbacks.registered {
+		result = append(result, registered.toFallback(subdomain))
+	}
+	for subdomain, route := range m.fallbacks.config.Routes {
+		if _, ok := m.fallbacks.registered[subdomain]; ok {
+			continue
+		}
+		result = append(result, Fallback{Subdomain: subdomain, Route: route, Source: FallbackSourceConfig})
+	}
+	for subdomain, route := range m.fallbacks.internal {
+		_, isRegistered := m.fallbacks.registered[subdomain]
+		_, isConfigured := m.fallbacks.config.Routes[subdomain]
+		if isRegistered || isConfigured {
+			continue
+		}
+		result = append(result, Fallback{Subdomain: subdomain, Route: route, Source: FallbackSourceInternal})
+	}
+
+	slices.SortFunc(result, func(a, b Fallback) int {
+		return strings.Compare(a.Subdomain, b.Subdomain)
+	})
+
+	return result
+}
+
+// Fallback returns the fallback route used for a subdomain or ErrNotFound
+func (m Manager) Fallback(subdomain string) (Fallback, error) {
+	subdomain, err := NormalizeSubdomain(subdomain)
+	if err != nil {
+		return Fallback{}, err
+	}
+
+	m.fallbacks.mu.RLock()
+	defer m.fallbacks.mu.RUnlock()
+
+	registered, ok := m.fallbacks.registered[subdomain]
+	if ok {
+		return registered.toFallback(subdomain), nil
+	}
+
+	route, ok := m.fallbacks.config.Routes[subdomain]
+	if ok {
+		return Fallback{Subdomain: subdomain, Route: route, Source: FallbackSourceConfig}, nil
+	}
+
+	route, ok = m.fallbacks.internal[subdomain]
+	if ok {
+		return Fallback{Subdomain: subdomain, Route: route, Source: FallbackSourceInternal}, nil
+	}
+
+	return Fallback{}, fmt.Errorf("%w: fallback for subdomain %q", ErrNotFound, subdomain)
+}
+
+// RemoveFallback removes the registered and configured fallback routes for a subdomain. A route
+// from the config file will return if the file is reloaded and still contains it. Internal routes can't be removed
+func (m Manager) RemoveFallback(subdomain string) error {
+	subdomain, err := NormalizeSubdomain(subdomain)
+	if err != nil {
+		return err
+	}
+
+	m.fallbacks.mu.Lock()
+	defer m.fallbacks.mu.Unlock()
+
+	registered, ok := m.fallbacks.registered[subdomain]
+	if !ok {
+		if _, ok := m.fallbacks.config.Routes[subdomain]; ok {
+			return fmt.Errorf("%w: remove %q from the file instead", ErrConfiguredRoute, subdomain)
+		}
+		if _, ok := m.fallbacks.internal[subdomain]; ok {
+			return fmt.Errorf("%w: %q is used by Goblin", ErrReservedSubdomain, subdomain)
+		}
+		return fmt.Errorf("%w: fallback for subdomain %q", ErrNotFound, subdomain)
+	}
+
+	delete(m.fallbacks.registered, subdomain)
+	m.events.publish(Event{Type: EventFallbackRemoved, Subdomain: subdomain, Target: registered.Target})
+
+	return nil
+}
+
+// IPPool returns the status of each IP alias in the subnet
+func (m Manager) IPPool() (IPPool, error) {
+	ipIter, err := m.getIPs()
+	if err != nil {
+		return IPPool{}, fmt.Errorf("error getting IPs from system: %w", err)
+	}
+
+	reservations := map[string]string{}
+	m.fallbacks.mu.RLock()
+	for subdomain, ip := range m.fallbacks.config.Reservations {
+		reservations[ip] = subdomain
+	}
+	m.fallbacks.mu.RUnlock()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	pool := IPPool{
+		Subnet: m.subnet.String(),
+		IPs:    []IPStatus{},
+	}
+	for ip := range ipIter {
+		status := IPStatus{
+			IP:          ip.String(),
+			Status:      IPStatusAvailable,
+			ReservedFor: reservations[ip.String()],
+		}
+
+		rec := m.allocatedIPs[ip.String()]
+		switch {
+		case rec == nil:
+			pool.Available++
+		case rec.isActive():
+			status.Status = IPStatusActive
+			status.Subdomain = rec.subdomain
+			pool.Active++
+		default:
+			status.Status = IPStatusReleased
+			status.Subdomain = rec.subdomain
+			pool.Released++
+		}
+
+		pool.Total++
+		pool.IPs = append(pool.IPs, status)
+	}
+
+	return pool, nil
+}
+
+// Status is a summary of the server's state
+type Status struct {
+	Active    []Record         `json:"active"`
+	Released  []Record         `json:"released"`
+	Fallbacks []FallbackStatus `json:"fallbacks"`
+}
+
+// FallbackStatus includes the current IP that a fallback route resolves to
+type FallbackStatus struct {
+	Fallback
+	IP    string `json:"ip,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Status returns active and released records and the fallback routes with their resolved IPs. Routes are resolved
+// concurrently, and each lookup is limited by statusLookupTimeout and the context
+func (m Manager) Status(ctx context.Context) Status {
+	status := Status{
+		Active:    []Record{},
+		Released:  []Record{},
+		Fallbacks: []FallbackStatus{},
+	}
+
+	for _, rec := range m.Records() {
+		if rec.Active {
+			status.Active = append(status.Active, rec)
+		} else {
+			status.Released = append(status.Released, rec)
+		}
+	}
+
+	for _, fallback := range m.Fallbacks() {
+		status.Fallbacks = append(status.Fallbacks, FallbackStatus{Fallback: fallback})
+	}
+
+	var wg sync.WaitGroup
+	for i := range status.Fallbacks {
+		fs := &status.Fallbacks[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			lookupCtx, cancel := context.WithTimeout(ctx, statusLookupTimeout)
+			defer cancel()
+
+			ip, err := m.routeCache.resolve(lookupCtx, fs.Route)
+			if err != nil {
+				fs.Error = err.Error()
+			} else {
+				fs.IP = ip.String()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return status
+}
@@ -0,0 +1,155 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeResolver is a DNS server for ProxyOptions.Resolver that answers every query with ip, or never answers if
+// ip is nil
+type fakeResolver struct {
+	addr    string
+	queries atomic.Int32
+}
+
+func newFakeResolver(t *testing.T, ip net.IP) *fakeResolver {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	r := &fakeResolver{addr: conn.LocalAddr().String()}
+	m := newTestManager(t)
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			r.queries.Add(1)
+
+			if ip == nil {
+				continue
+			}
+			_, _ = conn.WriteTo(m.createDNSResponse(questionOnly(buf[:n]), ip.To4(), time.Minute), addr)
+		}
+	}()
+
+	return r
+}
+
+// questionOnly removes the additional records, like EDNS options, from a request since createDNSResponse expects
+// the request to end after the question
+func questionOnly(request []byte) []byte {
+	end := 12
+	for end < len(request) && request[end] != 0 {
+		end += int(request[end]) + 1
+	}
+	// the name's terminating zero, type, and class
+	end += 5
+
+	result := append([]byte{}, request[:end]...)
+	result[10], result[11] = 0, 0
+	return result
+}
+
+func TestStatusResolvesConcurrently(t *testing.T) {
+	unresponsive := newFakeResolver(t, nil)
+
+	m := newTestManager(t)
+	err := m.SetFallbackConfig(FallbackConfig{Routes: FallbackRoutes{
+		"one": {Target: "one.example.com", Proxy: &ProxyOptions{Resolver: unresponsive.addr}},
+		"two": {Target: "two.example.com", Proxy: &ProxyOptions{Resolver: unresponsive.addr}},
+		"ip":  {Target: "192.168.1.10"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		lookupTimeout time.Duration
+		ctxTimeout    time.Duration
+		maxElapsed    time.Duration
+	}{
+		// the lookups would take twice as long one at a time
+		{"LookupTimeout", 300 * time.Millisecond, time.Minute, 550 * time.Millisecond},
+		{"ContextTimeout", time.Minute, 100 * time.Millisecond, 250 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := statusLookupTimeout
+			statusLookupTimeout = tt.lookupTimeout
+			t.Cleanup(func() { statusLookupTimeout = original })
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.ctxTimeout)
+			defer cancel()
+
+			start := time.Now()
+			status := m.Status(ctx)
+			elapsed := time.Since(start)
+
+			if elapsed > tt.maxElapsed {
+				t.Errorf("expected lookups to take less than %s, took %s", tt.maxElapsed, elapsed)
+			}
+
+			for _, fs := range status.Fallbacks {
+				switch fs.Subdomain {
+				case "ip":
+					if fs.IP != "192.168.1.10" || fs.Error != "" {
+						t.Errorf("expected IP for %q, got %+v", fs.Subdomain, fs)
+					}
+				default:
+					if fs.Error == "" {
+						t.Errorf("expected error for %q, got %+v", fs.Subdomain, fs)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStatusCachesForTTL(t *testing.T) {
+	tests := []struct {
+		name            string
+		ttl             time.Duration
+		expectedLookups int32
+	}{
+		{"NoTTL", 0, 2},
+		{"TTL", time.Minute, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := newFakeResolver(t, net.ParseIP("192.168.1.20"))
+
+			m := newTestManager(t)
+			err := m.SetFallbackConfig(FallbackConfig{Routes: FallbackRoutes{
+				"app": {Target: "app.example.com", TTL: Duration(tt.ttl), Proxy: &ProxyOptions{Resolver: resolver.addr}},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for range 2 {
+				status := m.Status(context.Background())
+				if len(status.Fallbacks) != 1 || status.Fallbacks[0].IP != "192.168.1.20" {
+					t.Fatalf("unexpected fallbacks: %+v", status.Fallbacks)
+				}
+			}
+
+			if n := resolver.queries.Load(); n != tt.expectedLookups {
+				t.Errorf("expected %d lookups, got %d", tt.expectedLookups, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package dns
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// maxLabelLength is the maximum length of each dot-separated part of a domain name from RFC 1123
+const maxLabelLength = 63
+
+// NormalizeSubdomain converts a subdomain to the form used in DNS queries so names that only differ by case
+// are the same. Internationalized names are converted to punycode, and each label must be a valid RFC 1123
+// hostname label. The returned error wraps ErrInvalidSubdomain and explains the problem
+func NormalizeSubdomain(subdomain string) (string, error) {
+	if subdomain == "" {
+		return "", fmt.Errorf("%w: subdomain is empty", ErrInvalidSubdomain)
+	}
+
+	normalized := strings.ToLower(subdomain)
+	if !isASCII(normalized) {
+		var err error
+		normalized, err = idna.Lookup.ToASCII(subdomain)
+		if err != nil {
+			return "", fmt.Errorf("%w %q: %w", ErrInvalidSubdomain, subdomain, err)
+		}
+	}
+
+	for _, label := range strings.Split(normalized, ".") {
+		err := validateLabel(label)
+		if err != nil {
+			return "", fmt.Errorf("%w %q: %w", ErrInvalidSubdomain, subdomain, err)
+		}
+	}
+
+	return normalized, nil
+}
+
+// validateLabel checks that a lowercase label only has letters, digits, and hyphens, doesn't start or end with
+// a hyphen, and is not too long
+func validateLabel(label string) error {
+	switch {
+	case label == "":
+		return fmt.Errorf("empty label")
+	case len(label) > maxLabelLength:
+		return fmt.Errorf("label %q is longer than %d characters", label, maxLabelLength)
+	case label[0] == '-' || label[len(label)-1] == '-':
+		return fmt.Errorf("label %q starts or ends with a hyphen", label)
+	}
+
+	for _, r := range label {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return fmt.Errorf("label %q has invalid character %q, only letters, digits, and hyphens are allowed", label, r)
+		}
+	}
+
+	return nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
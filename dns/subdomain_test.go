@@ -0,0 +1,57 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+func TestNormalizeSubdomain(t *testing.T) {
+	tests := []struct {
+		name      string
+		subdomain string
+		expected  string
+		expectErr bool
+	}{
+		{"Lowercase", "app", "app", false},
+		{"CaseFolding", "MyApp", "myapp", false},
+		{"MultipleLabels", "API.Staging", "api.staging", false},
+		{"DigitsAndHyphens", "app-2", "app-2", false},
+		{"StartsWithDigit", "1app", "1app", false},
+		{"IDN", "bücher", "xn--bcher-kva", false},
+		{"IDNCaseFolding", "Bücher", "xn--bcher-kva", false},
+		{"IDNMultipleLabels", "café.api", "xn--caf-dma.api", false},
+		{"Punycode", "xn--bcher-kva", "xn--bcher-kva", false},
+		{"MaxLength", strings.Repeat("a", maxLabelLength), strings.Repeat("a", maxLabelLength), false},
+		{"Empty", "", "", true},
+		{"Underscore", "my_app", "", true},
+		{"LeadingHyphen", "-app", "", true},
+		{"TrailingHyphen", "app-", "", true},
+		{"TrailingHyphenInLabel", "app-.api", "", true},
+		{"EmptyLabel", "app..api", "", true},
+		{"TrailingDot", "app.", "", true},
+		{"TooLong", strings.Repeat("a", maxLabelLength+1), "", true},
+		{"IDNTooLong", strings.Repeat("ü", maxLabelLength), "", true},
+		{"Space", "my app", "", true},
+		{"Slash", "app/api", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized, err := NormalizeSubdomain(tt.subdomain)
+			if tt.expectErr {
+				if !errors.Is(err, ErrInvalidSubdomain) {
+					t.Errorf("expected ErrInvalidSubdomain, got %q, %v", normalized, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if normalized != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, normalized)
+			}
+		})
+	}
+}
@@ -8,6 +8,8 @@ import (
 var (
 	// copy these functions here to avoid package name conflict
 	New = errors.New
+	Is  = errors.Is
+	As  = errors.As
 )
 
 type UserFixableError struct {
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/calvinmclean/goblin/goblin"
+)
+
+func main() {}
+
+func Run(ctx context.Context, env goblin.Env) error {
+	log.Printf("starting server for %s on %s", env.FQDN, env.Addr())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "I am %s running at %s\n", env.FQDN, env.Addr())
+	})
+	mux.HandleFunc("GET /lookup/{subdomain}", func(w http.ResponseWriter, r *http.Request) {
+		ip, err := env.Lookup(r.Context(), r.PathValue("subdomain"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, ip)
+	})
+
+	server := &http.Server{
+		Addr:      env.Addr(),
+		Handler:   mux,
+		TLSConfig: env.TLS,
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		err := server.Shutdown(context.Background())
+		if err != nil {
+			log.Printf("failed to stop server: %v", err)
+		}
+	}()
+
+	var err error
+	if env.TLS != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("failed to run server: %w", err)
+	}
+
+	wg.Wait()
+
+	return nil
+}
@@ -2,4 +2,17 @@ module github.com/calvinmclean/goblin
 
 go 1.23.4
 
-require github.com/urfave/cli/v3 v3.0.0-beta1
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/urfave/cli/v3 v3.0.0-beta1
+	golang.org/x/net v0.32.0
+	google.golang.org/grpc v1.70.0
+	google.golang.org/protobuf v1.36.5
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20241202173237-19429a94021a // indirect
+)
@@ -0,0 +1,36 @@
+// Package goblin has the details that Goblin passes to plugins when they run. It only uses the standard library,
+// so plugins can import it without adding dependencies that have to match Goblin's versions
+package goblin
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strconv"
+)
+
+// DefaultPort is the suggested port when one isn't configured
+const DefaultPort = 8080
+
+// Env describes where a plugin runs. It is passed to plugins that implement:
+//
+//	func Run(ctx context.Context, env goblin.Env) error
+type Env struct {
+	// IP is allocated for the plugin to listen on
+	IP string
+	// Subdomain and Domain make up the FQDN that resolves to the IP
+	Subdomain string
+	Domain    string
+	FQDN      string
+	// Port is the suggested port to listen on
+	Port int
+	// TLS has the configured certificate for serving the FQDN, or it is nil if TLS isn't configured
+	TLS *tls.Config
+	// Lookup gets the IP that DNS answers with for another subdomain. It is nil if Goblin can't look up addresses
+	Lookup func(ctx context.Context, subdomain string) (string, error)
+}
+
+// Addr is the IP and Port to listen on, like "10.0.0.4:8080"
+func (e Env) Addr() string {
+	return net.JoinHostPort(e.IP, strconv.Itoa(e.Port))
+}
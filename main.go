@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"os"
 
 	"github.com/calvinmclean/goblin/cmd"
@@ -12,19 +12,25 @@ import (
 
 func main() {
 	app := &cli.Command{
-		Name: "goblin",
+		Name:   "goblin",
+		Flags:  cmd.LoggingFlags,
+		Before: cmd.SetupLogging,
 		Commands: []*cli.Command{
 			cmd.ClientCmd,
 			cmd.ServerCmd,
 			cmd.ExampleCmd,
 			cmd.RunCmd,
+			cmd.UpCmd,
 			cmd.RegisterCmd,
+			cmd.UnregisterCmd,
 			cmd.DockerCmd,
+			cmd.ListCmd,
 		},
 	}
 
-	err := app.Run(context.Background(), os.Args)
+	err := app.Run(context.Background(), cmd.SplitExecArgs(os.Args))
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("error running command", "error", err)
+		os.Exit(1)
 	}
 }
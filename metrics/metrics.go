@@ -0,0 +1,241 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// DefaultBuckets are histogram buckets in seconds that work well for local DNS and network latency
+var DefaultBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// Registry holds metrics and writes them in the Prometheus text exposition format. This implements the
+// small subset of Prometheus features that Goblin needs so it doesn't require a client library
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes all metrics in the Prometheus text format
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range r.metrics {
+		m.write(w)
+	}
+}
+
+// ServeHTTP allows using the Registry as the handler for a metrics endpoint
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.Write(w)
+}
+
+// Counter is a value that only increases, optionally partitioned by labels
+type Counter struct {
+	name, help string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// Counter creates and registers a new Counter
+func (r *Registry) Counter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{name: name, help: help, labelNames: labelNames, values: map[string]float64{}}
+	r.register(c)
+	return c
+}
+
+// Inc adds 1 to the counter with the label values, which must be in the same order as the label names
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds to the counter with the label values, which must be in the same order as the label names
+func (c *Counter) Add(v float64, labelValues ...string) {
+	key := labelKey(c.labelNames, labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values[key] += v
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, key, formatFloat(c.values[key]))
+	}
+}
+
+// Histogram counts observations in buckets, optionally partitioned by labels
+type Histogram struct {
+	name, help string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+// Histogram creates and registers a new Histogram. If buckets is nil, DefaultBuckets are used
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+
+	h := &Histogram{name: name, help: help, labelNames: labelNames, buckets: buckets, values: map[string]*histogramValue{}}
+	r.register(h)
+	return h
+}
+
+// Observe adds a value to the histogram with the label values, which must be in the same order as the label names
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := labelKey(h.labelNames, labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{labelValues: labelValues, counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+
+	for i, upperBound := range h.buckets {
+		if v <= upperBound {
+			hv.counts[i]++
+		}
+	}
+	hv.count++
+	hv.sum += v
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+
+		bucketLabels := append(slices.Clone(h.labelNames), "le")
+		for i, upperBound := range h.buckets {
+			labels := labelKey(bucketLabels, append(slices.Clone(hv.labelValues), formatFloat(upperBound)))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels, hv.counts[i])
+		}
+		labels := labelKey(bucketLabels, append(slices.Clone(hv.labelValues), "+Inf"))
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels, hv.count)
+
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, key, formatFloat(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, key, hv.count)
+	}
+}
+
+// gaugeFunc reads its values from a function when metrics are collected
+type gaugeFunc struct {
+	name, help string
+	labelName  string
+	fn         func() map[string]float64
+}
+
+// GaugeFunc registers a gauge that calls fn to get the current values when metrics are collected. The keys of
+// the returned map are values for the label. If labelName is empty, the map should only have an empty key
+func (r *Registry) GaugeFunc(name, help, labelName string, fn func() map[string]float64) {
+	r.register(&gaugeFunc{name, help, labelName, fn})
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	values := map[string]float64{}
+	for labelValue, v := range g.fn() {
+		if g.labelName == "" {
+			values[""] = v
+			continue
+		}
+		values[labelKey([]string{g.labelName}, []string{labelValue})] = v
+	}
+
+	writeHeader(w, g.name, g.help, "gauge")
+	for _, key := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, key, formatFloat(values[key]))
+	}
+}
+
+func writeHeader(w io.Writer, name, help, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+}
+
+// labelKey formats labels like {name="value"}, which is also used as the key for storing values
+func labelKey(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		fmt.Fprintf(&sb, `%s="%s"`, name, labelValueEscaper.Replace(value))
+	}
+	sb.WriteString("}")
+
+	return sb.String()
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
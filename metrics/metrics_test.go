@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(r *Registry)
+		expected string
+	}{
+		{
+			"Empty",
+			func(*Registry) {},
+			"",
+		},
+		{
+			"CounterWithoutLabels",
+			func(r *Registry) {
+				c := r.Counter("goblin_test_total", "Test counter")
+				c.Inc()
+				c.Add(1.5)
+			},
+			`# HELP goblin_test_total Test counter
+# TYPE goblin_test_total counter
+goblin_test_total 2.5
+`,
+		},
+		{
+			"CounterWithLabelsIsSorted",
+			func(r *Registry) {
+				c := r.Counter("goblin_queries_total", "DNS queries", "subdomain", "result")
+				c.Inc("web", "local")
+				c.Inc("app", "miss")
+				c.Inc("app", "local")
+				c.Inc("app", "local")
+			},
+			`# HELP goblin_queries_total DNS queries
+# TYPE goblin_queries_total counter
+goblin_queries_total{subdomain="app",result="local"} 2
+goblin_queries_total{subdomain="app",result="miss"} 1
+goblin_queries_total{subdomain="web",result="local"} 1
+`,
+		},
+		{
+			"CounterMissingLabelValue",
+			func(r *Registry) {
+				c := r.Counter("goblin_test_total", "Test counter", "a", "b")
+				c.Inc("x")
+			},
+			`# HELP goblin_test_total Test counter
+# TYPE goblin_test_total counter
+goblin_test_total{a="x",b=""} 1
+`,
+		},
+		{
+			"LabelEscaping",
+			func(r *Registry) {
+				c := r.Counter("goblin_test_total", "Test counter", "value")
+				c.Inc(`back\slash`)
+				c.Inc(`"quoted"`)
+				c.Inc("new\nline")
+			},
+			`# HELP goblin_test_total Test counter
+# TYPE goblin_test_total counter
+goblin_test_total{value="\"quoted\""} 1
+goblin_test_total{value="back\\slash"} 1
+goblin_test_total{value="new\nline"} 1
+`,
+		},
+		{
+			"Histogram",
+			func(r *Registry) {
+				h := r.Histogram("goblin_duration_seconds", "Duration", []float64{0.1, 1}, "type")
+				h.Observe(0.05, "A")
+				h.Observe(0.5, "A")
+				h.Observe(2, "A")
+				h.Observe(0.1, `"AAAA"`)
+			},
+			`# HELP goblin_duration_seconds Duration
+# TYPE goblin_duration_seconds histogram
+goblin_duration_seconds_bucket{type="A",le="0.1"} 1
+goblin_duration_seconds_bucket{type="A",le="1"} 2
+goblin_duration_seconds_bucket{type="A",le="+Inf"} 3
+goblin_duration_seconds_sum{type="A"} 2.55
+goblin_duration_seconds_count{type="A"} 3
+goblin_duration_seconds_bucket{type="\"AAAA\"",le="0.1"} 1
+goblin_duration_seconds_bucket{type="\"AAAA\"",le="1"} 1
+goblin_duration_seconds_bucket{type="\"AAAA\"",le="+Inf"} 1
+goblin_duration_seconds_sum{type="\"AAAA\""} 0.1
+goblin_duration_seconds_count{type="\"AAAA\""} 1
+`,
+		},
+		{
+			"HistogramWithoutLabels",
+			func(r *Registry) {
+				h := r.Histogram("goblin_duration_seconds", "Duration", []float64{1})
+				h.Observe(3)
+			},
+			`# HELP goblin_duration_seconds Duration
+# TYPE goblin_duration_seconds histogram
+goblin_duration_seconds_bucket{le="1"} 0
+goblin_duration_seconds_bucket{le="+Inf"} 1
+goblin_duration_seconds_sum 3
+goblin_duration_seconds_count 1
+`,
+		},
+		{
+			"GaugeFunc",
+			func(r *Registry) {
+				r.GaugeFunc("goblin_records", "Records by state", "state", func() map[string]float64 {
+					return map[string]float64{"ready": 3, "pending": 1, `a"b`: 0}
+				})
+			},
+			`# HELP goblin_records Records by state
+# TYPE goblin_records gauge
+goblin_records{state="a\"b"} 0
+goblin_records{state="pending"} 1
+goblin_records{state="ready"} 3
+`,
+		},
+		{
+			"GaugeFuncWithoutLabel",
+			func(r *Registry) {
+				r.GaugeFunc("goblin_uptime_seconds", "Uptime", "", func() map[string]float64 {
+					return map[string]float64{"": 12.5}
+				})
+			},
+			`# HELP goblin_uptime_seconds Uptime
+# TYPE goblin_uptime_seconds gauge
+goblin_uptime_seconds 12.5
+`,
+		},
+		{
+			"RegistrationOrder",
+			func(r *Registry) {
+				r.Counter("goblin_b_total", "B").Inc()
+				r.Counter("goblin_a_total", "A").Inc()
+			},
+			`# HELP goblin_b_total B
+# TYPE goblin_b_total counter
+goblin_b_total 1
+# HELP goblin_a_total A
+# TYPE goblin_a_total counter
+goblin_a_total 1
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			tt.setup(r)
+
+			var sb strings.Builder
+			r.Write(&sb)
+
+			if sb.String() != tt.expected {
+				t.Errorf("unexpected output:\n%s\nexpected:\n%s", sb.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("goblin_test_total", "Test counter").Inc()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	expectedType := "text/plain; version=0.0.4; charset=utf-8"
+	if w.Header().Get("Content-Type") != expectedType {
+		t.Errorf("expected Content-Type %q, got %q", expectedType, w.Header().Get("Content-Type"))
+	}
+
+	expected := "# HELP goblin_test_total Test counter\n# TYPE goblin_test_total counter\ngoblin_test_total 1\n"
+	if w.Body.String() != expected {
+		t.Errorf("expected %q, got %q", expected, w.Body.String())
+	}
+}
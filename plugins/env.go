@@ -0,0 +1,53 @@
+package plugins
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	"github.com/calvinmclean/goblin/goblin"
+)
+
+// EnvConfig has the details for the goblin.Env that is passed to plugins each time they run
+type EnvConfig struct {
+	Subdomain string
+	Domain    string
+	// Port is the suggested port. The default is goblin.DefaultPort
+	Port   int
+	TLS    *tls.Config
+	Lookup func(ctx context.Context, subdomain string) (string, error)
+}
+
+// Env creates the goblin.Env for a plugin running with the IP
+func (c EnvConfig) Env(ip string) goblin.Env {
+	env := goblin.Env{
+		IP:        ip,
+		Subdomain: c.Subdomain,
+		Domain:    c.Domain,
+		Port:      c.Port,
+		TLS:       c.TLS,
+		Lookup:    c.Lookup,
+	}
+	if env.Port == 0 {
+		env.Port = goblin.DefaultPort
+	}
+	if c.Subdomain != "" && c.Domain != "" {
+		env.FQDN = c.Subdomain + "." + c.Domain
+	}
+	// each run gets its own copy so plugins can't change the config for other runs
+	if c.TLS != nil {
+		env.TLS = c.TLS.Clone()
+	}
+
+	return env
+}
+
+// LoadTLSConfig creates a TLS config for goblin.Env from certificate and key files
+func LoadTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading TLS certificate: %w", err)
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
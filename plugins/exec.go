@@ -0,0 +1,148 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+const (
+	// DefaultIPEnvVar is the environment variable that has the IP for executables when another isn't chosen
+	DefaultIPEnvVar = "GOBLIN_IP"
+
+	// stopTimeout is how long a process has to exit after it is interrupted before it is killed
+	stopTimeout = 10 * time.Second
+)
+
+// ErrStopped is returned when the process exits after Goblin forwarded a signal that stops it, like Ctrl+C, so
+// it can be told apart from a crash and isn't restarted
+var ErrStopped = errors.New("stopped by signal")
+
+// ExecData is used to execute templates in the arguments for an executable, like --addr={{.IP}}:8080
+type ExecData struct {
+	IP string
+}
+
+// ExecOptions configure how Exec runs an executable
+type ExecOptions struct {
+	// IPEnvVar is the environment variable that has the IP. DefaultIPEnvVar is used if it's empty
+	IPEnvVar string
+	// Env has extra environment variables for the process, like KEY=value
+	Env []string
+	// Dir is the working directory. The current directory is used if it's empty
+	Dir string
+	// Stdout and Stderr receive the process's output. The defaults are os.Stdout and os.Stderr
+	Stdout, Stderr io.Writer
+	// ForwardSignals sends signals received by Goblin to the process. Otherwise, it is only interrupted
+	// when the context is done
+	ForwardSignals bool
+}
+
+// Exec creates a RunFunc that runs an executable as a child process. The IP is set in the environment variable
+// from the options, and it can be used in args with a template like {{.IP}}. RunFunc returns when the process
+// exits. When the context is done, the process is interrupted and then killed if it doesn't exit within 10 seconds
+func Exec(path string, args []string, opts ExecOptions) (RunFunc, error) {
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return nil, errors.NewUserFixableError(err, "\nDoes the file exist and is it executable?\n")
+	}
+
+	templates := make([]*template.Template, 0, len(args))
+	for _, arg := range args {
+		tmpl, err := template.New("arg").Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template in argument %q: %w", arg, err)
+		}
+		templates = append(templates, tmpl)
+	}
+
+	if opts.IPEnvVar == "" {
+		opts.IPEnvVar = DefaultIPEnvVar
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+
+	return func(ctx context.Context, ipAddr string) error {
+		data := ExecData{IP: ipAddr}
+
+		args := make([]string, 0, len(templates))
+		for _, tmpl := range templates {
+			var arg strings.Builder
+			err := tmpl.Execute(&arg, data)
+			if err != nil {
+				return fmt.Errorf("error executing argument template: %w", err)
+			}
+			args = append(args, arg.String())
+		}
+
+		cmd := exec.CommandContext(ctx, path, args...)
+		cmd.Env = append(os.Environ(), opts.Env...)
+		cmd.Env = append(cmd.Env, opts.IPEnvVar+"="+ipAddr)
+		cmd.Dir = opts.Dir
+		cmd.Stdout = opts.Stdout
+		cmd.Stderr = opts.Stderr
+		cmd.Cancel = func() error {
+			return cmd.Process.Signal(os.Interrupt)
+		}
+		cmd.WaitDelay = stopTimeout
+		setProcessGroup(cmd)
+
+		return runProcess(cmd, opts.ForwardSignals)
+	}, nil
+}
+
+// runProcess starts the command and optionally forwards signals to it until it exits
+func runProcess(cmd *exec.Cmd, forwardSignals bool) error {
+	// signals are handled before starting so Goblin doesn't exit and release the IP while the process is running
+	var signals chan os.Signal
+	if forwardSignals {
+		signals = make(chan os.Signal, 1)
+		signal.Notify(signals, forwardedSignals...)
+		defer signal.Stop(signals)
+	}
+
+	err := cmd.Start()
+	if err != nil {
+		return fmt.Errorf("error starting process: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	stopped := false
+	for {
+		select {
+		case sig := <-signals:
+			_ = cmd.Process.Signal(sig)
+			if sig == os.Interrupt || sig == syscall.SIGTERM {
+				stopped = true
+			}
+		case err := <-done:
+			stopProcessGroup(cmd)
+			switch {
+			case stopped && err != nil:
+				return fmt.Errorf("%w: process exited: %w", ErrStopped, err)
+			case stopped:
+				return ErrStopped
+			case err != nil:
+				return fmt.Errorf("process exited: %w", err)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package plugins
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// forwardedSignals are sent to the process when Goblin receives them
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+func setProcessGroup(*exec.Cmd) {}
+
+func stopProcessGroup(*exec.Cmd) {}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package plugins
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// forwardedSignals are sent to the process when Goblin receives them
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2}
+
+// setProcessGroup runs the process in its own process group so signals from the terminal, like Ctrl+C, are only
+// received once when Goblin forwards them
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// stopProcessGroup kills processes that were started by the process and are still running after it exits, so they
+// don't keep running without the IP or keep its output open
+func stopProcessGroup(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -2,20 +2,29 @@ package plugins
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"plugin"
 	"runtime"
+	"strings"
 
 	"github.com/calvinmclean/goblin/errors"
+	"github.com/calvinmclean/goblin/goblin"
 )
 
 const (
 	lookupRunErrorInstruction = `
 One of the following functions must be implemented in the main package:
     func Run(ctx context.Context, ipAddress string) error
+    func Run(ctx context.Context, env goblin.Env) error
     func Run(ctx context.Context) error // requires --env flag
 `
 
@@ -27,12 +36,21 @@ If you recently updated Go, you should reinstall Goblin.
 
 Goblin strives to have minimal dependencies, but it's possible your plugin has a different version of:
     - github.com/urfave/cli/v3
+    - github.com/BurntSushi/toml
+    - gopkg.in/yaml.v3
+    - golang.org/x/net
+    - golang.org/x/text
+
+When Goblin is built with the grpc build tag, it also uses:
+    - google.golang.org/grpc
+    - google.golang.org/protobuf
 `
 )
 
 type RunFunc func(context.Context, string) error
 
-func Load(fname string) (RunFunc, error) {
+// Load opens a plugin and gets its Run function. Plugins that use goblin.Env get it from env each time they run
+func Load(fname string, env EnvConfig) (RunFunc, error) {
 	_, err := os.Stat(fname)
 	if err != nil {
 		return nil, errors.NewUserFixableError(err, "\nDoes the file exist?\n")
@@ -48,12 +66,16 @@ func Load(fname string) (RunFunc, error) {
 		return nil, errors.NewUserFixableError(err, lookupRunErrorInstruction)
 	}
 
-	runFunc, ok := runSymb.(func(context.Context, string) error)
-	if !ok {
+	switch runFunc := runSymb.(type) {
+	case func(context.Context, string) error:
+		return RunFunc(runFunc), nil
+	case func(context.Context, goblin.Env) error:
+		return func(ctx context.Context, ipAddr string) error {
+			return runFunc(ctx, env.Env(ipAddr))
+		}, nil
+	default:
 		return nil, errors.NewUserFixableError(fmt.Errorf("incorrect type: %T", runSymb), lookupRunErrorInstruction)
 	}
-
-	return RunFunc(runFunc), nil
 }
 
 func LoadMainWithIPEnvVar(fname, ipEnvVar string) (RunFunc, error) {
@@ -98,7 +120,7 @@ func Run(ctx context.Context, run RunFunc, getter IPGetter, subdomain string) er
 		return fmt.Errorf("error getting IP: %w", err)
 	}
 
-	return run(ctx, ip)
+	return safeRun(ctx, run, ip)
 }
 
 // Build will use `go build -buildmode=plugin` to build a Plugin and return the path to the .so file
@@ -128,3 +150,101 @@ func Build(path string) (string, error) {
 
 	return filepath.Join(path, pluginName) + ".so", nil
 }
+
+// BuildOptions are extra options for go build
+type BuildOptions struct {
+	// Flags are passed to go build, like -race
+	Flags []string
+	// Tags is a comma-separated list of build tags
+	Tags string
+}
+
+// BuildExecutable will use `go build` to build a normal executable from a main package and return its path. The
+// executable is put in the user's cache directory so the source directory isn't changed
+func BuildExecutable(path string, opts BuildOptions) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	err = CheckMain(absPath, opts.Tags)
+	if err != nil {
+		return "", err
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cache directory: %w", err)
+	}
+
+	// each source directory gets its own directory so executables with the same name don't conflict
+	hash := sha256.Sum256([]byte(absPath))
+	out := filepath.Join(cacheDir, "goblin", "bin", hex.EncodeToString(hash[:8]), filepath.Base(absPath))
+
+	args := []string{"build", "-o", out}
+	if opts.Tags != "" {
+		args = append(args, "-tags", opts.Tags)
+	}
+	args = append(args, opts.Flags...)
+	args = append(args, ".")
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = absPath
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.NewUserFixableError(err, string(output))
+	}
+
+	return out, nil
+}
+
+// CheckMain returns an error if the package in dir doesn't have a main function that does anything. Plugins often
+// have no main function or an empty one since only Run is used, so building them as an executable would fail or
+// exit right away instead of calling Run
+func CheckMain(dir, tags string) error {
+	buildCtx := build.Default
+	if tags != "" {
+		buildCtx.BuildTags = strings.Split(tags, ",")
+	}
+
+	pkg, err := buildCtx.ImportDir(dir, 0)
+	if err != nil {
+		// go build reports a better error for packages that can't be loaded
+		return nil
+	}
+
+	var hasMain, hasRun bool
+	fset := token.NewFileSet()
+	for _, fname := range append(pkg.GoFiles, pkg.CgoFiles...) {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, fname), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil
+		}
+
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil {
+				continue
+			}
+
+			switch fn.Name.Name {
+			case "main":
+				hasMain = fn.Body != nil && len(fn.Body.List) > 0
+			case "Run":
+				hasRun = true
+			}
+		}
+	}
+
+	if hasMain || !hasRun {
+		return nil
+	}
+
+	return errors.NewUserFixableError(
+		fmt.Errorf("%s is a plugin without a main function to run as an executable", dir),
+		"Only main() runs in an executable, so the plugin's Run function would never be called. Run it as a plugin"+
+			" without --subprocess or --watch, or add a main function that calls Run with the IP from the "+
+			DefaultIPEnvVar+" environment variable",
+	)
+}
@@ -0,0 +1,91 @@
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+func TestCheckMain(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		tags      string
+		expectErr bool
+	}{
+		{
+			"MainPackage",
+			map[string]string{"main.go": "package main\n\nfunc main() { println(\"hi\") }\n"},
+			"",
+			false,
+		},
+		{
+			"MainCallsRun",
+			map[string]string{"main.go": "package main\n\nfunc main() { _ = Run() }\n\nfunc Run() error { return nil }\n"},
+			"",
+			false,
+		},
+		{
+			"RunWithoutMain",
+			map[string]string{"main.go": "package main\n\nfunc Run() error { return nil }\n"},
+			"",
+			true,
+		},
+		{
+			"RunWithEmptyMain",
+			map[string]string{"main.go": "package main\n\nfunc main() {}\n\nfunc Run() error { return nil }\n"},
+			"",
+			true,
+		},
+		{
+			"MethodNamedRun",
+			map[string]string{"main.go": "package main\n\ntype s struct{}\n\nfunc (s) Run() {}\n\nfunc main() {}\n"},
+			"",
+			false,
+		},
+		{
+			"MainInTaggedFile",
+			map[string]string{
+				"run.go":  "package main\n\nfunc Run() error { return nil }\n",
+				"main.go": "//go:build dev\n\npackage main\n\nfunc main() { _ = Run() }\n",
+			},
+			"dev",
+			false,
+		},
+		{
+			"MainExcludedByTags",
+			map[string]string{
+				"run.go":  "package main\n\nfunc Run() error { return nil }\n",
+				"main.go": "//go:build dev\n\npackage main\n\nfunc main() { _ = Run() }\n",
+			},
+			"",
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for fname, contents := range tt.files {
+				err := os.WriteFile(filepath.Join(dir, fname), []byte(contents), 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := CheckMain(dir, tt.tags)
+			if tt.expectErr {
+				var userErr errors.UserFixableError
+				if !errors.As(err, &userErr) {
+					t.Errorf("expected UserFixableError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
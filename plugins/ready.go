@@ -0,0 +1,191 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+const (
+	// DefaultProbeInterval is how often a Probe is checked until it passes
+	DefaultProbeInterval = 500 * time.Millisecond
+
+	// probeTimeout limits each check so a hanging service doesn't block the next one
+	probeTimeout = 2 * time.Second
+)
+
+var errProbeConfig = errors.New("readiness probe needs a port or command")
+
+// Probe checks if a service running at the IP is ready to be used. It returns an error if it's not ready
+type Probe func(ctx context.Context, ip string) error
+
+// ReadyGetter allocates IPs that DNS doesn't use instead of the subdomain's fallback route until SetReady is called.
+// SetPending switches back to the fallback route
+type ReadyGetter interface {
+	IPGetter
+	GetPendingIP(ctx context.Context, subdomain string) (string, error)
+	SetReady(ctx context.Context, subdomain string) error
+	SetPending(ctx context.Context, subdomain string) error
+}
+
+// ProbeConfig chooses how NewProbe checks if a service is ready
+type ProbeConfig struct {
+	// Port is checked with a TCP connection, or an HTTP GET request if Path is set
+	Port int
+	Path string
+	// Command is run instead of checking the Port. Its arguments can use the IP with a template like {{.IP}},
+	// and it has the IP in the DefaultIPEnvVar environment variable
+	Command []string
+	// Dir is the working directory for the Command
+	Dir string
+}
+
+// NewProbe creates a Probe from the config
+func NewProbe(cfg ProbeConfig) (Probe, error) {
+	switch {
+	case len(cfg.Command) > 0:
+		return CommandProbe(cfg.Command, cfg.Dir)
+	case cfg.Port == 0:
+		return nil, errProbeConfig
+	case cfg.Path != "":
+		return HTTPProbe(cfg.Port, cfg.Path), nil
+	default:
+		return TCPProbe(cfg.Port), nil
+	}
+}
+
+// TCPProbe is ready when the port accepts connections
+func TCPProbe(port int) Probe {
+	return func(ctx context.Context, ip string) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// HTTPProbe is ready when a GET request to the path at the port responds with a 2xx or 3xx status
+func HTTPProbe(port int, path string) Probe {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	client := &http.Client{
+		// redirects might go to the subdomain, which isn't routed here until the service is ready
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return func(ctx context.Context, ip string) error {
+		u := "http://" + net.JoinHostPort(ip, strconv.Itoa(port)) + path
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// CommandProbe is ready when the command exits successfully. It runs in dir, or the current directory if it's empty
+func CommandProbe(command []string, dir string) (Probe, error) {
+	if len(command) == 0 {
+		return nil, errProbeConfig
+	}
+
+	templates := make([]*template.Template, 0, len(command))
+	for _, arg := range command {
+		tmpl, err := template.New("arg").Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template in probe command %q: %w", arg, err)
+		}
+		templates = append(templates, tmpl)
+	}
+
+	return func(ctx context.Context, ip string) error {
+		args := make([]string, 0, len(templates))
+		for _, tmpl := range templates {
+			var arg strings.Builder
+			err := tmpl.Execute(&arg, ExecData{IP: ip})
+			if err != nil {
+				return fmt.Errorf("error executing argument template: %w", err)
+			}
+			args = append(args, arg.String())
+		}
+
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Env = append(os.Environ(), DefaultIPEnvVar+"="+ip)
+		cmd.Dir = dir
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}, nil
+}
+
+// WaitReady checks the probe every interval until it passes. It only returns an error if the context is done first
+func WaitReady(ctx context.Context, probe Probe, ip string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+		err := probe(checkCtx, ip)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReportReady waits for the probe to pass and then sets the subdomain's IP as ready. It is used in a goroutine
+// while the plugin runs and stops early if the context is done. It returns true if the IP was set as ready
+func ReportReady(ctx context.Context, getter ReadyGetter, subdomain, ip string, probe Probe, interval time.Duration, logger *slog.Logger) bool {
+	err := WaitReady(ctx, probe, ip, interval)
+	if err != nil {
+		return false
+	}
+
+	err = getter.SetReady(ctx, subdomain)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error("error setting IP ready", "subdomain", subdomain, "error", err)
+		}
+		return false
+	}
+
+	logger.Info("ready", "subdomain", subdomain, "ip", ip)
+	return true
+}
@@ -0,0 +1,9 @@
+package plugins
+
+import "github.com/calvinmclean/goblin/dns"
+
+// the server's Manager and the API client are both used for readiness probes and restarts
+var (
+	_ ReadyGetter = dns.Manager{}
+	_ ReadyGetter = dns.Client{}
+)
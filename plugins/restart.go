@@ -0,0 +1,138 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+
+	// minRestartDelay is the first delay before restarting, and it doubles each time the plugin exits again up
+	// to maxRestartDelay
+	minRestartDelay = time.Second
+	maxRestartDelay = 30 * time.Second
+)
+
+// ErrGettingIP is returned by RunWithOptions when the IP can't be allocated, so it can be told apart from errors
+// returned by the plugin
+var ErrGettingIP = errors.New("error getting IP")
+
+// PanicError is returned when a RunFunc panics instead of crashing Goblin. Panics in goroutines started by the
+// plugin can't be recovered
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e PanicError) Error() string {
+	return fmt.Sprintf("plugin panicked: %v", e.Value)
+}
+
+// safeRun recovers a panic from the RunFunc and returns it as a PanicError. The stack is printed like an
+// unrecovered panic so it can be debugged
+func safeRun(ctx context.Context, run RunFunc, ip string) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		stack := debug.Stack()
+		fmt.Fprintf(os.Stderr, "panic: %v\n\n%s\n", r, stack)
+		err = PanicError{Value: r, Stack: stack}
+	}()
+
+	return run(ctx, ip)
+}
+
+// RunOptions configure RunWithOptions
+type RunOptions struct {
+	// Probe checks when the plugin is ready after each start. Without a Probe, it's ready when it starts
+	Probe Probe
+	// ProbeInterval is how often the Probe is checked. The default is DefaultProbeInterval
+	ProbeInterval time.Duration
+	// Restart is when the plugin is run again after it returns: RestartNever (default), RestartOnFailure, or
+	// RestartAlways. Panics are failures
+	Restart string
+	// OnReady is called each time the plugin is ready
+	OnReady func()
+	// Logger defaults to slog.Default()
+	Logger *slog.Logger
+}
+
+// RunWithOptions is like Run, but the IP is pending until the plugin is ready, and the plugin can be restarted
+// with backoff. The IP is kept between restarts, but DNS uses the subdomain's fallback route until the plugin is
+// ready again so clients keep working
+func RunWithOptions(ctx context.Context, run RunFunc, getter ReadyGetter, subdomain string, opts RunOptions) error {
+	if opts.Probe == nil {
+		opts.Probe = func(context.Context, string) error { return nil }
+	}
+	if opts.ProbeInterval == 0 {
+		opts.ProbeInterval = DefaultProbeInterval
+	}
+	if opts.Restart == "" {
+		opts.Restart = RestartNever
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	ip, err := getter.GetPendingIP(ctx, subdomain)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrGettingIP, err)
+	}
+
+	delay := minRestartDelay
+	for {
+		opts.Logger.Info("starting", "subdomain", subdomain, "ip", ip)
+
+		startedAt := time.Now()
+		err := runOnce(ctx, run, getter, subdomain, ip, opts)
+		// a process that was stopped with a forwarded signal isn't restarted so Ctrl+C stops Goblin too
+		if ctx.Err() != nil || errors.Is(err, ErrStopped) ||
+			opts.Restart == RestartNever || (err == nil && opts.Restart == RestartOnFailure) {
+			return err
+		}
+
+		pendingErr := getter.SetPending(ctx, subdomain)
+		if pendingErr != nil && ctx.Err() == nil {
+			opts.Logger.Error("error setting IP pending", "subdomain", subdomain, "error", pendingErr)
+		}
+
+		// a plugin that ran for a while is restarted quickly since it isn't crashing on startup
+		if time.Since(startedAt) > maxRestartDelay {
+			delay = minRestartDelay
+		}
+
+		opts.Logger.Warn("exited, restarting", "subdomain", subdomain, "error", err, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRestartDelay)
+	}
+}
+
+// runOnce runs the plugin until it returns and checks the probe while it runs
+func runOnce(ctx context.Context, run RunFunc, getter ReadyGetter, subdomain, ip string, opts RunOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		if ReportReady(ctx, getter, subdomain, ip, opts.Probe, opts.ProbeInterval, opts.Logger) && opts.OnReady != nil {
+			opts.OnReady()
+		}
+	}()
+
+	return safeRun(ctx, run, ip)
+}
@@ -0,0 +1,120 @@
+//go:build unix
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+// helperProcessEnvVar makes the test binary act as a child process that exits with an error on SIGINT
+const helperProcessEnvVar = "GOBLIN_TEST_HELPER_PROCESS"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperProcessEnvVar) == "1" {
+		runHelperProcess()
+	}
+
+	os.Exit(m.Run())
+}
+
+// runHelperProcess waits for SIGINT and then exits with an error, like a process that doesn't handle it
+func runHelperProcess() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+
+	fmt.Println("started")
+	<-signals
+	os.Exit(1)
+}
+
+// fakeReadyGetter counts how many times each subdomain is allocated
+type fakeReadyGetter struct {
+	mu          sync.Mutex
+	allocations int
+}
+
+func (g *fakeReadyGetter) GetIP(ctx context.Context, subdomain string) (string, error) {
+	return g.GetPendingIP(ctx, subdomain)
+}
+
+func (g *fakeReadyGetter) GetPendingIP(context.Context, string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.allocations++
+	return "127.0.0.1", nil
+}
+
+func (g *fakeReadyGetter) SetReady(context.Context, string) error   { return nil }
+func (g *fakeReadyGetter) SetPending(context.Context, string) error { return nil }
+
+// startedWriter closes started on the first write so the test knows the process is running
+type startedWriter struct {
+	once    sync.Once
+	started chan struct{}
+}
+
+func (w *startedWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	return len(p), nil
+}
+
+func TestRunWithOptionsStopsOnForwardedSignal(t *testing.T) {
+	tests := []struct {
+		restart string
+	}{
+		{RestartOnFailure},
+		{RestartAlways},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.restart, func(t *testing.T) {
+			t.Setenv(helperProcessEnvVar, "1")
+
+			stdout := &startedWriter{started: make(chan struct{})}
+			run, err := Exec(os.Args[0], nil, ExecOptions{Stdout: stdout, ForwardSignals: true})
+			if err != nil {
+				t.Fatalf("error creating RunFunc: %v", err)
+			}
+
+			getter := &fakeReadyGetter{}
+			done := make(chan error, 1)
+			go func() {
+				done <- RunWithOptions(context.Background(), run, getter, "helper", RunOptions{Restart: tt.restart})
+			}()
+
+			select {
+			case <-stdout.started:
+			case <-time.After(10 * time.Second):
+				t.Fatal("timed out waiting for process to start")
+			}
+
+			// Goblin receives Ctrl+C and forwards it to the process
+			err = syscall.Kill(os.Getpid(), syscall.SIGINT)
+			if err != nil {
+				t.Fatalf("error sending SIGINT: %v", err)
+			}
+
+			select {
+			case err := <-done:
+				if !errors.Is(err, ErrStopped) {
+					t.Errorf("expected ErrStopped, got %v", err)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("process was restarted instead of stopping")
+			}
+
+			if getter.allocations != 1 {
+				t.Errorf("expected 1 allocation, got %d", getter.allocations)
+			}
+		})
+	}
+}
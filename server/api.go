@@ -0,0 +1,242 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/calvinmclean/goblin/dns"
+	"github.com/calvinmclean/goblin/errors"
+)
+
+var (
+	errMissingSubdomain = errors.New("missing required subdomain path variable")
+	errMissingAddress   = errors.New("missing address")
+	errInvalidExpire    = errors.New("invalid expire duration")
+	errMissingContainer = errors.New("missing required container path variable")
+	errInvalidFilter    = errors.New("invalid filter")
+	errUnauthorized     = errors.New("missing or invalid API token")
+)
+
+// apiError chooses the response status code and error code for an error returned by the dns.Manager
+func apiError(err error) (int, dns.APIError) {
+	status, code := http.StatusInternalServerError, dns.ErrorCodeInternal
+	switch {
+	case errors.Is(err, errMissingSubdomain), errors.Is(err, dns.ErrInvalidSubdomain):
+		status, code = http.StatusBadRequest, dns.ErrorCodeInvalidSubdomain
+	case errors.Is(err, errMissingAddress), errors.Is(err, errInvalidExpire),
+		errors.Is(err, errMissingContainer), errors.Is(err, errInvalidFilter):
+		status, code = http.StatusBadRequest, dns.ErrorCodeInvalidRequest
+	case errors.Is(err, errUnauthorized):
+		status, code = http.StatusUnauthorized, dns.ErrorCodeUnauthorized
+	case errors.Is(err, dns.ErrNotFound):
+		status, code = http.StatusNotFound, dns.ErrorCodeNotFound
+	case errors.Is(err, dns.ErrReservedSubdomain):
+		status, code = http.StatusForbidden, dns.ErrorCodeReservedSubdomain
+	case errors.Is(err, dns.ErrConfiguredRoute):
+		status, code = http.StatusConflict, dns.ErrorCodeConfiguredRoute
+	case errors.Is(err, dns.ErrSubdomainInUse):
+		status, code = http.StatusConflict, dns.ErrorCodeSubdomainInUse
+	case errors.Is(err, dns.ErrNoAvailableIPs):
+		status, code = http.StatusServiceUnavailable, dns.ErrorCodeNoAvailableIPs
+	}
+
+	result := dns.APIError{Code: code, Message: err.Error()}
+
+	var userFixableErr errors.UserFixableError
+	if errors.As(err, &userFixableErr) {
+		result.Instructions = userFixableErr.Instructions
+	}
+
+	return status, result
+}
+
+func (s Server) writeError(w http.ResponseWriter, msg string, err error) {
+	s.logger.Error(msg, "error", err)
+
+	status, apiErr := apiError(err)
+	s.writeJSON(w, status, dns.ErrorResponse{Error: apiErr})
+}
+
+func (s Server) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		s.logger.Error("error writing response", "error", err)
+	}
+}
+
+func (s Server) listRecordsHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.mgr.Records())
+}
+
+func (s Server) getRecordHandler(w http.ResponseWriter, r *http.Request) {
+	rec, err := s.mgr.Record(r.PathValue("subdomain"))
+	if err != nil {
+		s.writeError(w, "error getting record", err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, rec)
+}
+
+func (s Server) lookupHandler(w http.ResponseWriter, r *http.Request) {
+	lookup, err := s.mgr.Lookup(r.PathValue("subdomain"))
+	if err != nil {
+		s.writeError(w, "error looking up subdomain", err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, lookup)
+}
+
+func (s Server) infoHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.mgr.Info())
+}
+
+func (s Server) listFallbacksHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.mgr.Fallbacks())
+}
+
+func (s Server) getFallbackHandler(w http.ResponseWriter, r *http.Request) {
+	fallback, err := s.mgr.Fallback(r.PathValue("subdomain"))
+	if err != nil {
+		s.writeError(w, "error getting fallback", err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, fallback)
+}
+
+func (s Server) deleteFallbackHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.mgr.RemoveFallback(r.PathValue("subdomain"))
+	if err != nil {
+		s.writeError(w, "error removing fallback", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s Server) ipPoolHandler(w http.ResponseWriter, r *http.Request) {
+	pool, err := s.mgr.IPPool()
+	if err != nil {
+		s.writeError(w, "error getting IP pool", err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, pool)
+}
+
+func (s Server) statusHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.mgr.Status(r.Context()))
+}
+
+func (s Server) registerDockerHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.registerDocker(w, r)
+	if err != nil {
+		s.writeError(w, "error registering docker container", err)
+		return
+	}
+}
+
+// registerDocker registers a fallback route to a local docker container's IP. The subdomain query
+// param defaults to the container name, and the optional network query param chooses the container's network
+func (s Server) registerDocker(w http.ResponseWriter, r *http.Request) error {
+	container := r.PathValue("container")
+	if container == "" {
+		return errMissingContainer
+	}
+
+	subdomain := r.URL.Query().Get("subdomain")
+	if subdomain == "" {
+		subdomain = container
+	}
+
+	// check the subdomain before looking up the container since container names are not always valid subdomains
+	_, err := dns.NormalizeSubdomain(subdomain)
+	if err != nil {
+		return err
+	}
+
+	expiresIn, err := parseExpire(r)
+	if err != nil {
+		return err
+	}
+
+	containerIP, err := s.docker.WithNetwork(r.URL.Query().Get("network")).ContainerIP(r.Context(), container)
+	if err != nil {
+		return fmt.Errorf("error getting IP for container: %w", err)
+	}
+
+	err = s.mgr.RegisterFallback(subdomain, containerIP, expiresIn)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+// parseExpire gets the optional expire duration from the query params
+func parseExpire(r *http.Request) (time.Duration, error) {
+	expire := r.URL.Query().Get("expire")
+	if expire == "" {
+		return 0, nil
+	}
+
+	expiresIn, err := time.ParseDuration(expire)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", errInvalidExpire, err)
+	}
+
+	return expiresIn, nil
+}
+
+func (s Server) queriesHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseQueryFilter(r)
+	if err != nil {
+		s.writeError(w, "error parsing query filter", err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, s.mgr.Queries(filter))
+}
+
+// parseQueryFilter reads filters from query params. The since param can be a timestamp in RFC3339 format
+// or a duration like 5m
+func parseQueryFilter(r *http.Request) (dns.QueryFilter, error) {
+	q := r.URL.Query()
+	filter := dns.QueryFilter{
+		Subdomain: q.Get("subdomain"),
+		Source:    q.Get("source"),
+		Type:      q.Get("type"),
+	}
+
+	if limit := q.Get("limit"); limit != "" {
+		var err error
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return dns.QueryFilter{}, fmt.Errorf("%w: limit: %w", errInvalidFilter, err)
+		}
+	}
+
+	if since := q.Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err == nil {
+			filter.Since = time.Now().Add(-d)
+			return filter, nil
+		}
+
+		filter.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return dns.QueryFilter{}, fmt.Errorf("%w: since must be a duration or RFC3339 timestamp", errInvalidFilter)
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/calvinmclean/goblin/dns"
+	"github.com/calvinmclean/goblin/errors"
+)
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name                 string
+		err                  error
+		expectedStatus       int
+		expectedCode         string
+		expectedInstructions string
+	}{
+		{"MissingSubdomain", errMissingSubdomain, http.StatusBadRequest, dns.ErrorCodeInvalidSubdomain, ""},
+		{"InvalidSubdomain", dns.ErrInvalidSubdomain, http.StatusBadRequest, dns.ErrorCodeInvalidSubdomain, ""},
+		{"MissingAddress", errMissingAddress, http.StatusBadRequest, dns.ErrorCodeInvalidRequest, ""},
+		{"InvalidExpire", errInvalidExpire, http.StatusBadRequest, dns.ErrorCodeInvalidRequest, ""},
+		{"MissingContainer", errMissingContainer, http.StatusBadRequest, dns.ErrorCodeInvalidRequest, ""},
+		{"InvalidFilter", errInvalidFilter, http.StatusBadRequest, dns.ErrorCodeInvalidRequest, ""},
+		{"Unauthorized", errUnauthorized, http.StatusUnauthorized, dns.ErrorCodeUnauthorized, ""},
+		{"NotFound", dns.ErrNotFound, http.StatusNotFound, dns.ErrorCodeNotFound, ""},
+		{"ReservedSubdomain", dns.ErrReservedSubdomain, http.StatusForbidden, dns.ErrorCodeReservedSubdomain, ""},
+		{"ConfiguredRoute", dns.ErrConfiguredRoute, http.StatusConflict, dns.ErrorCodeConfiguredRoute, ""},
+		{"SubdomainInUse", dns.ErrSubdomainInUse, http.StatusConflict, dns.ErrorCodeSubdomainInUse, ""},
+		{"NoAvailableIPs", dns.ErrNoAvailableIPs, http.StatusServiceUnavailable, dns.ErrorCodeNoAvailableIPs, ""},
+		{"Wrapped", fmt.Errorf("error getting IP: %w", dns.ErrSubdomainInUse), http.StatusConflict, dns.ErrorCodeSubdomainInUse, ""},
+		{"Other", errors.New("oops"), http.StatusInternalServerError, dns.ErrorCodeInternal, ""},
+		{
+			"UserFixable",
+			errors.NewUserFixableError(errors.New("docker is not running"), "start docker"),
+			http.StatusInternalServerError,
+			dns.ErrorCodeInternal,
+			"start docker",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, apiErr := apiError(tt.err)
+			if status != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, status)
+			}
+			if apiErr.Code != tt.expectedCode {
+				t.Errorf("expected code %q, got %q", tt.expectedCode, apiErr.Code)
+			}
+			if apiErr.Message != tt.err.Error() {
+				t.Errorf("expected message %q, got %q", tt.err.Error(), apiErr.Message)
+			}
+			if apiErr.Instructions != tt.expectedInstructions {
+				t.Errorf("expected instructions %q, got %q", tt.expectedInstructions, apiErr.Instructions)
+			}
+		})
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	_, srv := newTestServer(t, "", dns.FallbackRoutes{"config": {Target: "192.168.1.10"}})
+
+	tests := []struct {
+		name           string
+		method, path   string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"RecordNotFound", http.MethodGet, "/records/missing", http.StatusNotFound, dns.ErrorCodeNotFound},
+		{"LookupNotFound", http.MethodGet, "/lookup/missing", http.StatusNotFound, dns.ErrorCodeNotFound},
+		{"FallbackNotFound", http.MethodGet, "/fallbacks/missing", http.StatusNotFound, dns.ErrorCodeNotFound},
+		{"RemoveConfiguredRoute", http.MethodDelete, "/fallbacks/config", http.StatusConflict, dns.ErrorCodeConfiguredRoute},
+		{"InvalidSubdomain", http.MethodPost, "/register/-bad-?address=192.168.1.10", http.StatusBadRequest, dns.ErrorCodeInvalidSubdomain},
+		{"MissingAddress", http.MethodPost, "/register/db", http.StatusBadRequest, dns.ErrorCodeInvalidRequest},
+		{"InvalidExpire", http.MethodPost, "/register/db?address=192.168.1.10&expire=soon", http.StatusBadRequest, dns.ErrorCodeInvalidRequest},
+		{"InvalidFilter", http.MethodGet, "/queries?since=yesterday", http.StatusBadRequest, dns.ErrorCodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, http.NoBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+			if resp.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("expected JSON response, got %q", resp.Header.Get("Content-Type"))
+			}
+
+			apiErr := readAPIError(t, resp)
+			if apiErr.Code != tt.expectedCode {
+				t.Errorf("expected code %q, got %q", tt.expectedCode, apiErr.Code)
+			}
+		})
+	}
+}
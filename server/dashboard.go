@@ -0,0 +1,57 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net"
+	"net/http"
+)
+
+// dashboardSubdomain is registered as a fallback route so the dashboard is reachable at goblin.{domain}
+const dashboardSubdomain = "goblin"
+
+// dashboardPattern serves the dashboard's static files for any GET request that isn't handled by the API
+const dashboardPattern = "GET /"
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// dashboardHandler serves the static files for the web dashboard, which uses the JSON API
+func dashboardHandler() http.Handler {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		// this can only happen if the embed directive is changed
+		panic(err)
+	}
+	return http.FileServerFS(files)
+}
+
+// registerDashboardRoute routes the dashboard subdomain to the HTTP server's IP. It isn't registered if the
+// subdomain has a route in the config file, which is used instead
+func (s Server) registerDashboardRoute() {
+	// the dashboard is only available over TCP
+	if s.server.Addr == "" {
+		return
+	}
+
+	host, port, err := net.SplitHostPort(s.server.Addr)
+	if err != nil || host == "" {
+		s.logger.Warn("unable to register dashboard route", "addr", s.server.Addr, "error", err)
+		return
+	}
+
+	fallback, err := s.mgr.Fallback(dashboardSubdomain)
+	if err == nil {
+		s.logger.Info("dashboard route not registered because the subdomain already has a route",
+			"subdomain", dashboardSubdomain, "fallback", fallback.Route.Target)
+		return
+	}
+
+	err = s.mgr.RegisterInternalFallback(dashboardSubdomain, host)
+	if err != nil {
+		s.logger.Warn("unable to register dashboard route", "error", err)
+		return
+	}
+
+	s.logger.Info("dashboard is available", "url", "http://"+net.JoinHostPort(dashboardSubdomain+"."+s.mgr.Domain, port))
+}
@@ -0,0 +1,275 @@
+"use strict";
+
+const maxQueries = 50;
+const queries = [];
+let logsSubdomain = "";
+
+// the API token can be provided once with ?token= and is then kept in local storage
+const tokenKey = "goblin-token";
+const pageParams = new URLSearchParams(window.location.search);
+if (pageParams.has("token")) {
+    localStorage.setItem(tokenKey, pageParams.get("token"));
+    window.history.replaceState(null, "", window.location.pathname);
+}
+
+let promptedForToken = false;
+
+function token() {
+    return localStorage.getItem(tokenKey) || "";
+}
+
+async function api(method, path, params) {
+    const url = new URL(path, window.location.origin);
+    for (const [key, value] of Object.entries(params || {})) {
+        if (value) {
+            url.searchParams.set(key, value);
+        }
+    }
+
+    const headers = token() ? { Authorization: `Bearer ${token()}` } : {};
+    const resp = await fetch(url, { method, headers });
+    if (resp.status === 401 && !promptedForToken) {
+        promptedForToken = true;
+        const newToken = window.prompt("Enter the Goblin API token from the server's token file");
+        if (newToken) {
+            localStorage.setItem(tokenKey, newToken.trim());
+            window.location.reload();
+        }
+    }
+    if (!resp.ok) {
+        const body = await resp.text();
+        try {
+            const { error } = JSON.parse(body);
+            throw new Error(`${error.code}: ${error.message}`);
+        } catch (err) {
+            if (err instanceof SyntaxError) {
+                throw new Error(`${resp.status}: ${body.trim()}`);
+            }
+            throw err;
+        }
+    }
+    if (resp.status === 204 || resp.status === 201) {
+        return null;
+    }
+    return resp.json();
+}
+
+function cell(row, text, className) {
+    const td = document.createElement("td");
+    td.textContent = text;
+    if (className) {
+        td.className = className;
+    }
+    row.appendChild(td);
+    return td;
+}
+
+function button(row, text, onClick) {
+    const td = document.createElement("td");
+    const btn = document.createElement("button");
+    btn.textContent = text;
+    btn.addEventListener("click", onClick);
+    td.appendChild(btn);
+    row.appendChild(td);
+}
+
+function since(time) {
+    const seconds = Math.round((Date.now() - new Date(time).getTime()) / 1000);
+    if (seconds < 60) {
+        return `${seconds}s ago`;
+    }
+    if (seconds < 3600) {
+        return `${Math.round(seconds / 60)}m ago`;
+    }
+    return `${Math.round(seconds / 3600)}h ago`;
+}
+
+function renderRecords(status) {
+    const tbody = document.getElementById("records");
+    tbody.replaceChildren();
+
+    for (const rec of [...status.active, ...status.released]) {
+        const row = document.createElement("tr");
+        cell(row, rec.subdomain);
+        cell(row, rec.ip);
+        const state = rec.active ? (rec.pending ? "pending" : "active") : "released";
+        cell(row, state, `status ${state}`);
+        cell(row, since(rec.active ? rec.allocated_at : rec.removed_at));
+        button(row, "logs", () => {
+            logsSubdomain = rec.subdomain;
+            refreshLogs();
+        });
+        tbody.appendChild(row);
+    }
+}
+
+function renderFallbacks(status) {
+    const tbody = document.getElementById("fallbacks");
+    tbody.replaceChildren();
+
+    for (const fallback of status.fallbacks) {
+        const row = document.createElement("tr");
+        cell(row, fallback.subdomain);
+        cell(row, fallback.route.target);
+        if (fallback.error) {
+            cell(row, fallback.error, "status unhealthy");
+        } else {
+            cell(row, fallback.ip, "status healthy");
+        }
+        cell(row, fallback.source);
+        cell(row, fallback.expires_at ? new Date(fallback.expires_at).toLocaleString() : "never");
+        if (fallback.source === "internal" || fallback.source === "config") {
+            // routes from the config file and internal routes like the dashboard's can't be removed
+            cell(row, "");
+        } else {
+            button(row, "remove", async () => {
+                try {
+                    await api("DELETE", `/fallbacks/${encodeURIComponent(fallback.subdomain)}`);
+                } catch (err) {
+                    showError(err);
+                }
+                refresh();
+            });
+        }
+        tbody.appendChild(row);
+    }
+}
+
+function renderPool(pool) {
+    document.getElementById("pool-summary").textContent =
+        `${pool.subnet}: ${pool.active} active, ${pool.released} released, ${pool.available} available of ${pool.total}`;
+
+    const container = document.getElementById("pool");
+    container.replaceChildren();
+    for (const ip of pool.ips) {
+        const span = document.createElement("span");
+        span.className = ip.status;
+        span.textContent = ip.subdomain ? `${ip.ip} (${ip.subdomain})` : ip.ip;
+        if (ip.reserved_for) {
+            span.title = `reserved for ${ip.reserved_for}`;
+        }
+        container.appendChild(span);
+    }
+}
+
+function renderQueries() {
+    const tbody = document.getElementById("queries");
+    tbody.replaceChildren();
+
+    for (const query of queries) {
+        const row = document.createElement("tr");
+        cell(row, new Date(query.time).toLocaleTimeString());
+        cell(row, query.subdomain);
+        if (query.type === "dns_query_served") {
+            cell(row, query.ip, "status healthy");
+        } else {
+            cell(row, query.error, "status unhealthy");
+        }
+        tbody.appendChild(row);
+    }
+}
+
+async function loadQueries() {
+    try {
+        const entries = await api("GET", "/queries", { limit: maxQueries });
+        queries.splice(0, queries.length, ...entries.map((e) => ({
+            time: e.time,
+            subdomain: e.subdomain || e.name,
+            ip: e.answer,
+            error: e.error,
+            type: e.answer ? "dns_query_served" : "dns_query_missed",
+        })));
+        renderQueries();
+    } catch (err) {
+        showError(err);
+    }
+}
+
+async function refreshLogs() {
+    if (!logsSubdomain) {
+        return;
+    }
+
+    document.getElementById("logs-subdomain").textContent = `(${logsSubdomain})`;
+    try {
+        const lines = await api("GET", `/logs/${encodeURIComponent(logsSubdomain)}`, { limit: 200 });
+        const pre = document.getElementById("logs");
+        pre.textContent = lines.map((l) => `${new Date(l.time).toLocaleTimeString()} ${l.line}`).join("\n");
+        pre.scrollTop = pre.scrollHeight;
+    } catch (err) {
+        showError(err);
+    }
+}
+
+function showError(err) {
+    document.getElementById("form-error").textContent = err ? err.message : "";
+}
+
+async function refresh() {
+    try {
+        const [status, pool] = await Promise.all([api("GET", "/status"), api("GET", "/ips")]);
+        renderRecords(status);
+        renderFallbacks(status);
+        renderPool(pool);
+    } catch (err) {
+        showError(err);
+    }
+}
+
+function watchEvents() {
+    const connection = document.getElementById("connection");
+    // EventSource can't set headers, so the token is sent as a query param
+    const url = new URL("/events", window.location.origin);
+    if (token()) {
+        url.searchParams.set("token", token());
+    }
+    const events = new EventSource(url);
+
+    events.onopen = () => {
+        connection.textContent = "connected";
+        connection.className = "status connected";
+        refresh();
+    };
+
+    events.onerror = () => {
+        connection.textContent = "disconnected";
+        connection.className = "status disconnected";
+    };
+
+    for (const type of ["allocated", "ready", "pending", "released", "fallback_registered", "fallback_removed"]) {
+        events.addEventListener(type, refresh);
+    }
+    for (const type of ["dns_query_served", "dns_query_missed"]) {
+        events.addEventListener(type, (e) => {
+            queries.unshift(JSON.parse(e.data));
+            queries.splice(maxQueries);
+            renderQueries();
+        });
+    }
+}
+
+function submitForm(id, onSubmit) {
+    document.getElementById(id).addEventListener("submit", async (e) => {
+        e.preventDefault();
+        const data = Object.fromEntries(new FormData(e.target));
+        try {
+            await onSubmit(data);
+            e.target.reset();
+            showError(null);
+        } catch (err) {
+            showError(err);
+        }
+        refresh();
+    });
+}
+
+submitForm("register-form", (data) =>
+    api("POST", `/register/${encodeURIComponent(data.subdomain)}`, { address: data.address, expire: data.expire }));
+
+submitForm("docker-form", (data) =>
+    api("POST", `/docker/${encodeURIComponent(data.container)}`, { subdomain: data.subdomain, expire: data.expire }));
+
+refresh();
+loadQueries();
+watchEvents();
+setInterval(refreshLogs, 2000);
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// eventsHandler streams events from the dns.Manager as server-sent events until the client disconnects
+func (s Server) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeError(w, "error streaming events", errors.New("flush unsupported"))
+		return
+	}
+
+	events := s.mgr.Subscribe(r.Context())
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			s.logger.Error("error encoding event", "error", err)
+			continue
+		}
+
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calvinmclean/goblin/dns"
+)
+
+// readEvent reads the next server-sent event and returns its name and data
+func readEvent(t *testing.T, scanner *bufio.Scanner) (string, dns.Event) {
+	t.Helper()
+
+	var name string
+	var event dns.Event
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			return name, event
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event)
+			if err != nil {
+				t.Fatalf("error decoding event data: %v", err)
+			}
+		default:
+			t.Fatalf("unexpected line: %q", line)
+		}
+	}
+
+	t.Fatalf("stream ended: %v", scanner.Err())
+	return "", dns.Event{}
+}
+
+func TestEvents(t *testing.T) {
+	mgr, srv := newTestServer(t, "", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the handler subscribes before responding, so events published after this are received
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("expected event stream, got %q", resp.Header.Get("Content-Type"))
+	}
+
+	err = mgr.RegisterFallback("db", "192.168.1.10", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mgr.RemoveFallback("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		expectedType dns.EventType
+	}{
+		{"Registered", dns.EventFallbackRegistered},
+		{"Removed", dns.EventFallbackRemoved},
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, event := readEvent(t, scanner)
+			if name != string(tt.expectedType) || event.Type != tt.expectedType {
+				t.Errorf("expected %s event, got %q with type %q", tt.expectedType, name, event.Type)
+			}
+			if event.Subdomain != "db" || event.Target != "192.168.1.10" {
+				t.Errorf("unexpected event: %+v", event)
+			}
+			if event.Time.IsZero() {
+				t.Error("expected event time")
+			}
+		})
+	}
+}
+
+func TestEventsWithClient(t *testing.T) {
+	mgr, srv := newTestServer(t, "", nil)
+
+	client, err := dns.NewHTTPClient(strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := client.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mgr.RegisterFallback("db", "192.168.1.10", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-events:
+		if event.Type != dns.EventFallbackRegistered || event.Subdomain != "db" {
+			t.Errorf("unexpected event: %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	// the stream is closed when the client disconnects
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected events to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for events to close")
+	}
+}
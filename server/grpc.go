@@ -0,0 +1,275 @@
+//go:build grpc
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/calvinmclean/goblin/api"
+	"github.com/calvinmclean/goblin/auth"
+	"github.com/calvinmclean/goblin/dns"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcService implements the gRPC API using the same dns.Manager as the HTTP API
+type grpcService struct {
+	api.UnimplementedGoblinServer
+	s Server
+}
+
+// CheckGRPC returns an error with instructions for rebuilding if Goblin was built without the gRPC API
+func CheckGRPC() error {
+	return nil
+}
+
+// RunGRPC serves the gRPC API on the Server's gRPC address and Unix socket until the context is done. It
+// requires the same token as the HTTP API
+func (s Server) RunGRPC(ctx context.Context) error {
+	var listeners []net.Listener
+	if s.grpcAddr != "" {
+		ln, err := net.Listen("tcp", s.grpcAddr)
+		if err != nil {
+			return fmt.Errorf("error listening on %q: %w", s.grpcAddr, err)
+		}
+		listeners = append(listeners, ln)
+	}
+
+	if s.grpcSocket != "" {
+		ln, err := listenUnix(s.grpcSocket)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return fmt.Errorf("error listening on socket %q: %w", s.grpcSocket, err)
+		}
+		listeners = append(listeners, ln)
+	}
+
+	if len(listeners) == 0 {
+		return errors.New("no address or socket to serve the gRPC API on")
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(s.unaryRequireToken),
+		grpc.StreamInterceptor(s.streamRequireToken),
+	)
+	api.RegisterGoblinServer(grpcServer, grpcService{s: s})
+
+	// allocations and watches are long-lived streams, so they are closed instead of waiting for them to finish
+	go func() {
+		<-ctx.Done()
+		grpcServer.Stop()
+	}()
+
+	errs := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		s.logger.Info("started local gRPC server", "addr", ln.Addr().String(), "network", ln.Addr().Network())
+		go func() {
+			errs <- grpcServer.Serve(ln)
+		}()
+	}
+
+	// when one listener fails, stop the server so the others stop too
+	var result error
+	for range listeners {
+		err := <-errs
+		if err != nil && !errors.Is(err, grpc.ErrServerStopped) && result == nil {
+			result = err
+			grpcServer.Stop()
+		}
+	}
+
+	return result
+}
+
+func (g grpcService) Allocate(req *api.AllocateRequest, stream grpc.ServerStreamingServer[api.Lease]) error {
+	subdomain, err := dns.NormalizeSubdomain(req.GetSubdomain())
+	if err != nil {
+		return grpcError(err)
+	}
+
+	getIP := g.s.mgr.GetIP
+	if req.GetPending() {
+		getIP = g.s.mgr.GetPendingIP
+	}
+
+	// the IP is released when the stream's context is done
+	ip, err := getIP(stream.Context(), subdomain)
+	if err != nil {
+		return grpcError(fmt.Errorf("error getting IP: %w", err))
+	}
+
+	err = stream.Send(&api.Lease{Subdomain: subdomain, Ip: ip})
+	if err != nil {
+		return err
+	}
+
+	<-stream.Context().Done()
+	return nil
+}
+
+func (g grpcService) SetReady(ctx context.Context, req *api.SetReadyRequest) (*api.SetReadyResponse, error) {
+	err := g.s.mgr.SetReady(ctx, req.GetSubdomain())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &api.SetReadyResponse{}, nil
+}
+
+func (g grpcService) SetPending(ctx context.Context, req *api.SetPendingRequest) (*api.SetPendingResponse, error) {
+	err := g.s.mgr.SetPending(ctx, req.GetSubdomain())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &api.SetPendingResponse{}, nil
+}
+
+func (g grpcService) RegisterFallback(_ context.Context, req *api.RegisterFallbackRequest) (*api.RegisterFallbackResponse, error) {
+	if req.GetSubdomain() == "" {
+		return nil, grpcError(errMissingSubdomain)
+	}
+	if req.GetAddress() == "" {
+		return nil, grpcError(errMissingAddress)
+	}
+
+	err := g.s.mgr.RegisterFallback(req.GetSubdomain(), req.GetAddress(), req.GetExpire().AsDuration())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &api.RegisterFallbackResponse{}, nil
+}
+
+func (g grpcService) List(context.Context, *api.ListRequest) (*api.ListResponse, error) {
+	resp := &api.ListResponse{}
+
+	for _, r := range g.s.mgr.Records() {
+		rec := &api.Record{
+			Subdomain:   r.Subdomain,
+			Ip:          r.IP,
+			Active:      r.Active,
+			Pending:     r.Pending,
+			AllocatedAt: timestamppb.New(r.AllocatedAt),
+		}
+		if r.RemovedAt != nil {
+			rec.RemovedAt = timestamppb.New(*r.RemovedAt)
+		}
+		resp.Records = append(resp.Records, rec)
+	}
+
+	for _, f := range g.s.mgr.Fallbacks() {
+		fallback := &api.Fallback{
+			Subdomain: f.Subdomain,
+			Target:    f.Route.Target,
+			Source:    f.Source,
+		}
+		if f.Route.TTL > 0 {
+			fallback.Ttl = durationpb.New(time.Duration(f.Route.TTL))
+		}
+		if f.ExpiresAt != nil {
+			fallback.ExpiresAt = timestamppb.New(*f.ExpiresAt)
+		}
+		resp.Fallbacks = append(resp.Fallbacks, fallback)
+	}
+
+	return resp, nil
+}
+
+func (g grpcService) Remove(_ context.Context, req *api.RemoveRequest) (*api.RemoveResponse, error) {
+	if req.GetSubdomain() == "" {
+		return nil, grpcError(errMissingSubdomain)
+	}
+
+	err := g.s.mgr.RemoveFallback(req.GetSubdomain())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &api.RemoveResponse{}, nil
+}
+
+func (g grpcService) Watch(_ *api.WatchRequest, stream grpc.ServerStreamingServer[api.Event]) error {
+	for event := range g.s.mgr.Subscribe(stream.Context()) {
+		err := stream.Send(&api.Event{
+			Type:      string(event.Type),
+			Time:      timestamppb.New(event.Time),
+			Subdomain: event.Subdomain,
+			Ip:        event.IP,
+			Target:    event.Target,
+			Error:     event.Error,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// grpcError sets the gRPC status code for an error returned by the dns.Manager
+func grpcError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, errMissingSubdomain), errors.Is(err, errMissingAddress), errors.Is(err, dns.ErrInvalidSubdomain):
+		code = codes.InvalidArgument
+	case errors.Is(err, errUnauthorized):
+		code = codes.Unauthenticated
+	case errors.Is(err, dns.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, dns.ErrReservedSubdomain):
+		code = codes.PermissionDenied
+	case errors.Is(err, dns.ErrConfiguredRoute):
+		code = codes.FailedPrecondition
+	case errors.Is(err, dns.ErrSubdomainInUse):
+		code = codes.AlreadyExists
+	case errors.Is(err, dns.ErrNoAvailableIPs):
+		code = codes.ResourceExhausted
+	}
+
+	return status.Error(code, err.Error())
+}
+
+// checkToken returns an Unauthenticated error if the request's metadata doesn't have the Server's token
+func (s Server) checkToken(ctx context.Context) error {
+	if s.token == "" {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, header := range md.Get("authorization") {
+		if auth.ValidAuthorization(header, s.token) {
+			return nil
+		}
+	}
+
+	s.logger.Error("unauthenticated request", "error", errUnauthorized)
+	return grpcError(errUnauthorized)
+}
+
+func (s Server) unaryRequireToken(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	err := s.checkToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s Server) streamRequireToken(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := s.checkToken(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
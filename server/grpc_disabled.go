@@ -0,0 +1,30 @@
+//go:build !grpc
+
+package server
+
+import (
+	"context"
+
+	"github.com/calvinmclean/goblin/errors"
+)
+
+const grpcDisabledInstruction = `The gRPC API is only included when Goblin is built with the grpc build tag:
+
+  go install -tags grpc github.com/calvinmclean/goblin@latest
+`
+
+// errGRPCDisabled is returned when the gRPC API is used but Goblin was built without the grpc build tag
+var errGRPCDisabled = errors.NewUserFixableError(
+	errors.New("goblin was built without the gRPC API"),
+	grpcDisabledInstruction,
+)
+
+// CheckGRPC returns an error with instructions for rebuilding if Goblin was built without the gRPC API
+func CheckGRPC() error {
+	return errGRPCDisabled
+}
+
+// RunGRPC returns an error because Goblin was built without the gRPC API
+func (s Server) RunGRPC(context.Context) error {
+	return errGRPCDisabled
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/calvinmclean/goblin/dns"
+)
+
+// maxLogLines is the number of recent lines kept for each subdomain
+const maxLogLines = 500
+
+// LogLine is a line of output from a plugin
+type LogLine struct {
+	Time time.Time `json:"time"`
+	Line string    `json:"line"`
+}
+
+// logStore keeps recent output from plugins that is sent to the server by `goblin run`
+type logStore struct {
+	mu    sync.RWMutex
+	lines map[string][]LogLine
+}
+
+func newLogStore() *logStore {
+	return &logStore{lines: map[string][]LogLine{}}
+}
+
+func (s *logStore) add(subdomain, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lines := append(s.lines[subdomain], LogLine{time.Now(), line})
+	if len(lines) > maxLogLines {
+		lines = lines[len(lines)-maxLogLines:]
+	}
+	s.lines[subdomain] = lines
+}
+
+// get returns up to limit of the most recent lines. If limit is 0, all lines are returned
+func (s *logStore) get(subdomain string, limit int) []LogLine {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lines := s.lines[subdomain]
+	if limit > 0 && len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+
+	result := make([]LogLine, len(lines))
+	copy(result, lines)
+	return result
+}
+
+// receiveLogsHandler reads lines from the streaming request body until it is closed
+func (s Server) receiveLogsHandler(w http.ResponseWriter, r *http.Request) {
+	subdomain, err := dns.NormalizeSubdomain(r.PathValue("subdomain"))
+	if err != nil {
+		s.writeError(w, "error receiving logs", err)
+		return
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		s.logs.add(subdomain, scanner.Text())
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s Server) getLogsHandler(w http.ResponseWriter, r *http.Request) {
+	subdomain, err := dns.NormalizeSubdomain(r.PathValue("subdomain"))
+	if err != nil {
+		s.writeError(w, "error getting logs", err)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	s.writeJSON(w, http.StatusOK, s.logs.get(subdomain, limit))
+}
@@ -4,35 +4,94 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"sync"
 
+	"github.com/calvinmclean/goblin/auth"
+	"github.com/calvinmclean/goblin/containers"
 	"github.com/calvinmclean/goblin/dns"
 )
 
 // Server runs the backend DNS server and IP allocation server
 type Server struct {
 	mgr    dns.Manager
+	docker containers.Runtime
+	logs   *logStore
 	server *http.Server
 	logger *slog.Logger
+	// token is required to use the API when it is set
+	token string
+	// socket is the path to a Unix socket where the API is served when it is set
+	socket string
+	// grpcAddr and grpcSocket are where the gRPC API is served when they are set
+	grpcAddr   string
+	grpcSocket string
 }
 
-func New(mgr dns.Manager, addr string) Server {
+// New creates a Server. If logger is nil, slog.Default() is used
+func New(mgr dns.Manager, addr string, docker containers.Runtime, logger *slog.Logger) Server {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
 	return Server{
-		mgr,
-		&http.Server{
+		mgr:    mgr,
+		docker: docker,
+		logs:   newLogStore(),
+		server: &http.Server{
 			Addr: addr,
 		},
-		slog.Default(),
+		logger: logger.With("component", "server"),
 	}
 }
 
+// WithToken returns a copy of the Server that requires the token to use the API. The dashboard page
+// itself does not require the token, but it must be provided to the dashboard to use the API
+func (s Server) WithToken(token string) Server {
+	s.token = token
+	return s
+}
+
+// WithUnixSocket returns a copy of the Server that also serves the API on a Unix socket at the path. The socket
+// is only accessible by the current user
+func (s Server) WithUnixSocket(path string) Server {
+	s.socket = path
+	return s
+}
+
+// WithGRPC returns a copy of the Server that also serves the gRPC API on addr
+func (s Server) WithGRPC(addr string) Server {
+	s.grpcAddr = addr
+	return s
+}
+
+// WithGRPCSocket returns a copy of the Server that also serves the gRPC API on a Unix socket at the path. Like
+// WithUnixSocket, the socket is only accessible by the current user
+func (s Server) WithGRPCSocket(path string) Server {
+	s.grpcSocket = path
+	return s
+}
+
 func (s Server) Run(ctx context.Context) error {
 	var wg sync.WaitGroup
 	wg.Add(3)
 
+	if s.grpcAddr != "" || s.grpcSocket != "" {
+		wg.Add(1)
+		go func() {
+			err := s.RunGRPC(ctx)
+			if err != nil {
+				s.logger.Error("failed to serve gRPC", "error", err)
+				os.Exit(1)
+			}
+			wg.Done()
+		}()
+	}
+
 	go func() {
 		<-ctx.Done()
 		_ = s.server.Shutdown(context.Background())
@@ -42,7 +101,8 @@ func (s Server) Run(ctx context.Context) error {
 	go func() {
 		err := s.RunHTTP(ctx)
 		if err != nil {
-			log.Fatalf("failed to serve HTTP: %v", err)
+			s.logger.Error("failed to serve HTTP", "error", err)
+			os.Exit(1)
 		}
 		wg.Done()
 	}()
@@ -50,7 +110,8 @@ func (s Server) Run(ctx context.Context) error {
 	go func() {
 		err := s.mgr.RunDNS(ctx)
 		if err != nil {
-			log.Fatalf("failed to serve DNS: %v", err)
+			s.logger.Error("failed to serve DNS", "error", err)
+			os.Exit(1)
 		}
 		wg.Done()
 	}()
@@ -61,21 +122,145 @@ func (s Server) Run(ctx context.Context) error {
 }
 
 func (s Server) RunHTTP(ctx context.Context) error {
+	s.server.Handler = s.handler()
+
+	s.registerDashboardRoute()
+
+	// requests use the server's context so long-running responses are closed on shutdown
+	s.server.BaseContext = func(net.Listener) context.Context {
+		return ctx
+	}
+
+	var listeners []net.Listener
+	if s.server.Addr != "" {
+		ln, err := net.Listen("tcp", s.server.Addr)
+		if err != nil {
+			return fmt.Errorf("error listening on %q: %w", s.server.Addr, err)
+		}
+		listeners = append(listeners, ln)
+	}
+
+	if s.socket != "" {
+		ln, err := listenUnix(s.socket)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return fmt.Errorf("error listening on socket %q: %w", s.socket, err)
+		}
+		listeners = append(listeners, ln)
+	}
+
+	if len(listeners) == 0 {
+		return errors.New("no address or socket to serve the API on")
+	}
+
+	errs := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		s.logger.Info("started local HTTP server", "addr", ln.Addr().String(), "network", ln.Addr().Network())
+		go func() {
+			errs <- s.server.Serve(ln)
+		}()
+	}
+
+	// when one listener fails, shut down the server so the others stop too
+	var result error
+	for range listeners {
+		err := <-errs
+		if err != nil && !errors.Is(err, http.ErrServerClosed) && result == nil {
+			result = err
+			_ = s.server.Close()
+		}
+	}
+
+	return result
+}
+
+// listenUnix listens on a Unix socket that can only be used by the current user. A socket left behind by a
+// previous server is removed
+func listenUnix(path string) (net.Listener, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("error creating socket directory: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, errors.New("socket is already in use by another server")
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			return nil, fmt.Errorf("error removing old socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chmod(path, 0o600)
+	if err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("error setting socket permissions: %w", err)
+	}
+
+	return ln, nil
+}
+
+// handler routes requests to the API and dashboard and checks the token
+func (s Server) handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("POST /allocate/{subdomain}", s.allocateIPHandler)
+	mux.HandleFunc("POST /ready/{subdomain}", s.readyHandler)
+	mux.HandleFunc("DELETE /ready/{subdomain}", s.pendingHandler)
 	mux.HandleFunc("POST /register/{subdomain}", s.registerFallbackHandler)
-	s.server.Handler = mux
+	mux.HandleFunc("GET /records", s.listRecordsHandler)
+	mux.HandleFunc("GET /records/{subdomain}", s.getRecordHandler)
+	mux.HandleFunc("GET /lookup/{subdomain}", s.lookupHandler)
+	mux.HandleFunc("GET /info", s.infoHandler)
+	mux.HandleFunc("GET /fallbacks", s.listFallbacksHandler)
+	mux.HandleFunc("GET /fallbacks/{subdomain}", s.getFallbackHandler)
+	mux.HandleFunc("DELETE /fallbacks/{subdomain}", s.deleteFallbackHandler)
+	mux.HandleFunc("GET /ips", s.ipPoolHandler)
+	mux.HandleFunc("GET /status", s.statusHandler)
+	mux.HandleFunc("GET /events", s.eventsHandler)
+	mux.Handle("GET /metrics", s.mgr.Metrics())
+	mux.HandleFunc("GET /queries", s.queriesHandler)
+	mux.HandleFunc("POST /docker/{container}", s.registerDockerHandler)
+	mux.HandleFunc("POST /logs/{subdomain}", s.receiveLogsHandler)
+	mux.HandleFunc("GET /logs/{subdomain}", s.getLogsHandler)
+	mux.Handle(dashboardPattern, dashboardHandler())
 
-	s.logger.Info("started local HTTP server", "addr", s.server.Addr)
+	return s.requireToken(mux)
+}
 
-	return s.server.ListenAndServe()
+// requireToken responds with 401 Unauthorized for API requests that don't have the Server's token
+func (s Server) requireToken(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token == "" || auth.Authenticated(r, s.token) {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// allow loading the dashboard so it can prompt for the token
+		if _, pattern := mux.Handler(r); pattern == dashboardPattern {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		s.writeError(w, "unauthenticated request", errUnauthorized)
+	})
 }
 
 func (s Server) registerFallbackHandler(w http.ResponseWriter, r *http.Request) {
 	err := s.registerFallback(w, r)
 	if err != nil {
-		s.logger.Error("error registering fallback", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.writeError(w, "error registering fallback", err)
 		return
 	}
 }
@@ -83,15 +268,23 @@ func (s Server) registerFallbackHandler(w http.ResponseWriter, r *http.Request)
 func (s Server) registerFallback(w http.ResponseWriter, r *http.Request) error {
 	subdomain := r.PathValue("subdomain")
 	if subdomain == "" {
-		return errors.New("missing required subdomain path variable")
+		return errMissingSubdomain
 	}
 
 	address := r.URL.Query().Get("address")
 	if address == "" {
-		return errors.New("missing address")
+		return errMissingAddress
 	}
 
-	s.mgr.RegisterFallback(subdomain, address)
+	expiresIn, err := parseExpire(r)
+	if err != nil {
+		return err
+	}
+
+	err = s.mgr.RegisterFallback(subdomain, address, expiresIn)
+	if err != nil {
+		return err
+	}
 
 	w.WriteHeader(http.StatusCreated)
 	return nil
@@ -100,8 +293,7 @@ func (s Server) registerFallback(w http.ResponseWriter, r *http.Request) error {
 func (s Server) allocateIPHandler(w http.ResponseWriter, r *http.Request) {
 	err := s.allocateIP(w, r)
 	if err != nil {
-		s.logger.Error("error allocating IP", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.writeError(w, "error allocating IP", err)
 		return
 	}
 }
@@ -109,10 +301,16 @@ func (s Server) allocateIPHandler(w http.ResponseWriter, r *http.Request) {
 func (s Server) allocateIP(w http.ResponseWriter, r *http.Request) error {
 	subdomain := r.PathValue("subdomain")
 	if subdomain == "" {
-		return errors.New("missing required subdomain path variable")
+		return errMissingSubdomain
 	}
 
-	ip, err := s.mgr.GetIP(r.Context(), subdomain)
+	getIP := s.mgr.GetIP
+	// pending IPs are not used by DNS until the client says they're ready
+	if r.URL.Query().Get("pending") == "true" {
+		getIP = s.mgr.GetPendingIP
+	}
+
+	ip, err := getIP(r.Context(), subdomain)
 	if err != nil {
 		return fmt.Errorf("error getting IP: %w", err)
 	}
@@ -130,3 +328,23 @@ func (s Server) allocateIP(w http.ResponseWriter, r *http.Request) error {
 	<-r.Context().Done()
 	return nil
 }
+
+func (s Server) readyHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.mgr.SetReady(r.Context(), r.PathValue("subdomain"))
+	if err != nil {
+		s.writeError(w, "error setting IP ready", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s Server) pendingHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.mgr.SetPending(r.Context(), r.PathValue("subdomain"))
+	if err != nil {
+		s.writeError(w, "error setting IP pending", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/calvinmclean/goblin/dns"
+)
+
+// newTestServer serves the API with a dns.Manager that doesn't need the system's resolver file and IP aliases
+func newTestServer(t *testing.T, token string, fallbacks dns.FallbackRoutes) (dns.Manager, *httptest.Server) {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mgr, err := dns.NewUnchecked(dns.Config{Domain: "goblin", FallbackRoutes: fallbacks, Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(mgr, "", nil, logger).WithToken(token)
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(srv.Close)
+
+	return mgr, srv
+}
+
+// readAPIError decodes the error from an API response
+func readAPIError(t *testing.T, resp *http.Response) dns.APIError {
+	t.Helper()
+
+	var result dns.ErrorResponse
+	err := json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		t.Fatalf("error decoding error response: %v", err)
+	}
+	return result.Error
+}
+
+func TestRequireToken(t *testing.T) {
+	tests := []struct {
+		name           string
+		serverToken    string
+		method, path   string
+		authorization  string
+		expectedStatus int
+	}{
+		{"NoServerToken", "", http.MethodGet, "/info", "", http.StatusOK},
+		{"MissingToken", "abc123", http.MethodGet, "/info", "", http.StatusUnauthorized},
+		{"WrongToken", "abc123", http.MethodGet, "/info", "Bearer wrong", http.StatusUnauthorized},
+		{"NotBearer", "abc123", http.MethodGet, "/info", "abc123", http.StatusUnauthorized},
+		{"ValidToken", "abc123", http.MethodGet, "/info", "Bearer abc123", http.StatusOK},
+		{"ValidTokenQueryParam", "abc123", http.MethodGet, "/info?token=abc123", "", http.StatusOK},
+		{"WrongTokenQueryParam", "abc123", http.MethodGet, "/info?token=wrong", "", http.StatusUnauthorized},
+		{"MissingTokenForChange", "abc123", http.MethodPost, "/register/db?address=192.168.1.10", "", http.StatusUnauthorized},
+		{"MissingTokenForEvents", "abc123", http.MethodGet, "/events", "", http.StatusUnauthorized},
+		{"MissingTokenForMetrics", "abc123", http.MethodGet, "/metrics", "", http.StatusUnauthorized},
+		{"MissingTokenForUnknownPost", "abc123", http.MethodPost, "/", "", http.StatusUnauthorized},
+		{"DashboardIndexWithoutToken", "abc123", http.MethodGet, "/", "", http.StatusOK},
+		{"DashboardScriptWithoutToken", "abc123", http.MethodGet, "/app.js", "", http.StatusOK},
+		{"DashboardStyleWithoutToken", "abc123", http.MethodGet, "/style.css", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, srv := newTestServer(t, tt.serverToken, nil)
+
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, http.NoBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			if tt.expectedStatus != http.StatusUnauthorized {
+				return
+			}
+
+			if resp.Header.Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("expected WWW-Authenticate header, got %q", resp.Header.Get("WWW-Authenticate"))
+			}
+			apiErr := readAPIError(t, resp)
+			if apiErr.Code != dns.ErrorCodeUnauthorized {
+				t.Errorf("expected error code %q, got %q", dns.ErrorCodeUnauthorized, apiErr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/calvinmclean/goblin/dns"
+	"github.com/calvinmclean/goblin/errors"
+	"github.com/calvinmclean/goblin/plugins"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest lists services for Goblin to run together
+type Manifest struct {
+	Services map[string]Service `json:"services" yaml:"services" toml:"services"`
+}
+
+// Service is a plugin or executable that Goblin allocates an IP for and runs. Relative paths are relative to
+// the manifest file
+type Service struct {
+	// Plugin is a *.so plugin file or a directory to build it from
+	Plugin string `json:"plugin,omitempty" yaml:"plugin" toml:"plugin"`
+	// Subprocess builds the Plugin directory as a normal executable and runs it like Exec
+	Subprocess bool `json:"subprocess,omitempty" yaml:"subprocess" toml:"subprocess"`
+	// Exec is an executable to run as a child process. Names without a path are found in PATH
+	Exec string `json:"exec,omitempty" yaml:"exec" toml:"exec"`
+	// Subdomain defaults to the service's name
+	Subdomain string `json:"subdomain,omitempty" yaml:"subdomain" toml:"subdomain"`
+	// EnvVar is the environment variable that has the IP. For plugins, it uses the Run function without the IP
+	EnvVar string `json:"env_var,omitempty" yaml:"env_var" toml:"env_var"`
+	// Env, Args, and Dir are the environment variables, arguments, and working directory for executables.
+	// Args can use the IP with a template like {{.IP}}. Dir defaults to the manifest's directory
+	Env  map[string]string `json:"env,omitempty" yaml:"env" toml:"env"`
+	Args []string          `json:"args,omitempty" yaml:"args" toml:"args"`
+	Dir  string            `json:"dir,omitempty" yaml:"dir" toml:"dir"`
+	// DependsOn are services that are started before this one and stopped after it
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on" toml:"depends_on"`
+	// Restart is when the service is restarted after it exits: on-failure (default), always, or never
+	Restart string `json:"restart,omitempty" yaml:"restart" toml:"restart"`
+	// Port, TLSCert, and TLSKey are the suggested port and TLS certificate for plugins that use goblin.Env
+	Port    int    `json:"port,omitempty" yaml:"port" toml:"port"`
+	TLSCert string `json:"tls_cert,omitempty" yaml:"tls_cert" toml:"tls_cert"`
+	TLSKey  string `json:"tls_key,omitempty" yaml:"tls_key" toml:"tls_key"`
+	// Ready checks when the service is ready. Until then, services that depend on it aren't started and DNS
+	// uses the subdomain's fallback route
+	Ready *Readiness `json:"ready,omitempty" yaml:"ready" toml:"ready"`
+}
+
+// Readiness configures a readiness probe. The Port is checked with a TCP connection, or an HTTP GET request if
+// Path is set. Command is a custom check that is run instead, and it is ready when it exits successfully
+type Readiness struct {
+	Port    int      `json:"port,omitempty" yaml:"port" toml:"port"`
+	Path    string   `json:"path,omitempty" yaml:"path" toml:"path"`
+	Command []string `json:"command,omitempty" yaml:"command" toml:"command"`
+	// Interval is how often the probe is checked until it passes. The default is 500ms
+	Interval dns.Duration `json:"interval,omitempty" yaml:"interval" toml:"interval"`
+}
+
+// isExec is true if the service runs as a child process
+func (s Service) isExec() bool {
+	return s.Exec != "" || s.Subprocess
+}
+
+// LoadManifest reads and validates a JSON, YAML, or TOML manifest file. Defaults are set and relative paths
+// are resolved from the file's directory
+func LoadManifest(fname string) (Manifest, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("error reading file: %w", err)
+	}
+
+	var unmarshal func([]byte, any) error
+	switch filepath.Ext(fname) {
+	case ".json":
+		unmarshal = json.Unmarshal
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	case ".toml":
+		unmarshal = toml.Unmarshal
+	default:
+		return Manifest{}, fmt.Errorf("unsupported file type: %q", filepath.Ext(fname))
+	}
+
+	var m Manifest
+	err = unmarshal(data, &m)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("error parsing file: %w", err)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(fname))
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	m = m.withDefaults(dir)
+
+	err = m.Validate()
+	if err != nil {
+		return Manifest{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+// withDefaults returns a copy of the manifest with default values and paths relative to dir
+func (m Manifest) withDefaults(dir string) Manifest {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	result := Manifest{Services: map[string]Service{}}
+	for name, s := range m.Services {
+		if s.Subdomain == "" {
+			s.Subdomain = name
+		}
+		if s.Restart == "" {
+			s.Restart = plugins.RestartOnFailure
+		}
+
+		s.Plugin = resolve(s.Plugin)
+		s.TLSCert = resolve(s.TLSCert)
+		s.TLSKey = resolve(s.TLSKey)
+		if isPath(s.Exec) {
+			s.Exec = resolve(s.Exec)
+		}
+
+		if s.Ready != nil {
+			ready := *s.Ready
+			ready.Command = slices.Clone(ready.Command)
+			if len(ready.Command) > 0 && isPath(ready.Command[0]) {
+				ready.Command[0] = resolve(ready.Command[0])
+			}
+			if ready.Interval == 0 {
+				ready.Interval = dns.Duration(plugins.DefaultProbeInterval)
+			}
+			s.Ready = &ready
+		}
+
+		if s.isExec() {
+			s.Dir = resolve(s.Dir)
+			if s.Dir == "" {
+				s.Dir = dir
+			}
+		}
+
+		result.Services[name] = s
+	}
+
+	return result
+}
+
+// isPath is true if the command is a path instead of a name to find in PATH
+func isPath(command string) bool {
+	return strings.ContainsRune(command, '/') || strings.ContainsRune(command, filepath.Separator)
+}
+
+// Validate checks the manifest for mistakes before it is used
+func (m Manifest) Validate() error {
+	if len(m.Services) == 0 {
+		return errors.New("no services")
+	}
+
+	subdomains := map[string]string{}
+	pluginEnvVars := map[string]string{}
+	for _, name := range m.names() {
+		s := m.Services[name]
+
+		switch {
+		case s.Plugin == "" && s.Exec == "":
+			return fmt.Errorf("service %q needs a plugin or exec", name)
+		case s.Plugin != "" && s.Exec != "":
+			return fmt.Errorf("service %q can't have both plugin and exec", name)
+		case s.Subprocess && s.Plugin == "":
+			return fmt.Errorf("service %q needs a plugin directory to use subprocess", name)
+		case !s.isExec() && (len(s.Env) > 0 || len(s.Args) > 0 || s.Dir != ""):
+			return fmt.Errorf("service %q can only use env, args, and dir with exec or subprocess", name)
+		case s.isExec() && (s.Port != 0 || s.TLSCert != "" || s.TLSKey != ""):
+			return fmt.Errorf("service %q can only use port, tls_cert, and tls_key with plugins", name)
+		case (s.TLSCert == "") != (s.TLSKey == ""):
+			return fmt.Errorf("service %q needs both tls_cert and tls_key", name)
+		case s.Port < 0 || s.Port > 65535:
+			return fmt.Errorf("service %q has invalid port %d", name, s.Port)
+		}
+
+		if s.Ready != nil {
+			_, err := s.Ready.probe("")
+			if err != nil {
+				return fmt.Errorf("service %q has invalid ready: %w", name, err)
+			}
+			if s.Ready.Interval < 0 {
+				return fmt.Errorf("service %q has negative ready interval", name)
+			}
+		}
+
+		switch s.Restart {
+		case plugins.RestartOnFailure, plugins.RestartAlways, plugins.RestartNever:
+		default:
+			return fmt.Errorf("service %q has invalid restart %q", name, s.Restart)
+		}
+
+		subdomain, err := dns.NormalizeSubdomain(s.Subdomain)
+		if err != nil {
+			return fmt.Errorf("service %q: %w", name, err)
+		}
+		if other, ok := subdomains[subdomain]; ok {
+			return fmt.Errorf("services %q and %q use the same subdomain: %s", other, name, subdomain)
+		}
+		subdomains[subdomain] = name
+
+		// plugins run in Goblin's process, so they share its environment
+		if !s.isExec() && s.EnvVar != "" {
+			if other, ok := pluginEnvVars[s.EnvVar]; ok {
+				return fmt.Errorf("plugins %q and %q use the same env_var: %s", other, name, s.EnvVar)
+			}
+			pluginEnvVars[s.EnvVar] = name
+		}
+	}
+
+	_, err := m.startOrder()
+	return err
+}
+
+// names gets the service names in a consistent order
+func (m Manifest) names() []string {
+	names := make([]string, 0, len(m.Services))
+	for name := range m.Services {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// startOrder sorts the services so each one comes after its dependencies. Services are visited by name and
+// dependencies in the order they are listed so the result is always the same. An error is returned for unknown
+// dependencies and cycles
+func (m Manifest) startOrder() ([]string, error) {
+	var order []string
+	visited := map[string]bool{}
+	visiting := map[string]bool{}
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		path = append(path, name)
+		if visiting[name] {
+			return fmt.Errorf("dependency cycle: %s", strings.Join(path, " -> "))
+		}
+		if visited[name] {
+			return nil
+		}
+
+		visiting[name] = true
+		for _, dep := range m.Services[name].DependsOn {
+			if _, ok := m.Services[dep]; !ok {
+				return fmt.Errorf("service %q depends on unknown service %q", name, dep)
+			}
+
+			err := visit(dep, path)
+			if err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+		visited[name] = true
+
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range m.names() {
+		err := visit(name, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// probe creates the readiness Probe. Commands run in dir
+func (r Readiness) probe(dir string) (plugins.Probe, error) {
+	return plugins.NewProbe(plugins.ProbeConfig{
+		Port:    r.Port,
+		Path:    r.Path,
+		Command: r.Command,
+		Dir:     dir,
+	})
+}
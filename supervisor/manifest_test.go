@@ -0,0 +1,119 @@
+package supervisor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStartOrder(t *testing.T) {
+	// services builds a Manifest from each service's dependencies
+	services := func(deps map[string][]string) Manifest {
+		m := Manifest{Services: map[string]Service{}}
+		for name, dependsOn := range deps {
+			m.Services[name] = Service{DependsOn: dependsOn}
+		}
+		return m
+	}
+
+	tests := []struct {
+		name          string
+		manifest      Manifest
+		expected      []string
+		expectedError string
+	}{
+		{
+			"NoDependenciesSortedByName",
+			services(map[string][]string{"web": nil, "api": nil, "db": nil}),
+			[]string{"api", "db", "web"},
+			"",
+		},
+		{
+			"Chain",
+			services(map[string][]string{"a": {"b"}, "b": {"c"}, "c": nil}),
+			[]string{"c", "b", "a"},
+			"",
+		},
+		{
+			"DependencyAfterDependentByName",
+			services(map[string][]string{"api": {"worker"}, "db": nil, "worker": nil}),
+			[]string{"worker", "api", "db"},
+			"",
+		},
+		{
+			"DependenciesInListedOrder",
+			services(map[string][]string{"app": {"web", "db"}, "db": nil, "web": nil}),
+			[]string{"web", "db", "app"},
+			"",
+		},
+		{
+			"SharedDependencyOnce",
+			services(map[string][]string{"web": {"db", "api"}, "api": {"db"}, "db": nil}),
+			[]string{"db", "api", "web"},
+			"",
+		},
+		{
+			"Diamond",
+			services(map[string][]string{"a": {"c", "b"}, "b": {"d"}, "c": {"d"}, "d": nil}),
+			[]string{"d", "c", "b", "a"},
+			"",
+		},
+		{
+			"SelfCycle",
+			services(map[string][]string{"a": {"a"}}),
+			nil,
+			"dependency cycle: a -> a",
+		},
+		{
+			"Cycle",
+			services(map[string][]string{"a": {"b"}, "b": {"a"}}),
+			nil,
+			"dependency cycle: a -> b -> a",
+		},
+		{
+			"LongCycle",
+			services(map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}}),
+			nil,
+			"dependency cycle: a -> b -> c -> a",
+		},
+		{
+			"CycleAfterValidServices",
+			services(map[string][]string{"a": nil, "x": {"y"}, "y": {"z"}, "z": {"y"}}),
+			nil,
+			"dependency cycle: x -> y -> z -> y",
+		},
+		{
+			"MissingDependency",
+			services(map[string][]string{"web": {"db"}}),
+			nil,
+			`service "web" depends on unknown service "db"`,
+		},
+		{
+			"MissingTransitiveDependency",
+			services(map[string][]string{"web": {"api"}, "api": {"db"}}),
+			nil,
+			`service "api" depends on unknown service "db"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// run more than once since map iteration order is random
+			for range 10 {
+				order, err := tt.manifest.startOrder()
+				if tt.expectedError != "" {
+					if err == nil || err.Error() != tt.expectedError {
+						t.Fatalf("expected error %q, got %v", tt.expectedError, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if !slices.Equal(order, tt.expected) {
+					t.Fatalf("expected %v, got %v", tt.expected, order)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package supervisor
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// prefixWriter writes each line with a prefix so the output from services can be told apart
+type prefixWriter struct {
+	// mu is shared by all writers to out so their lines aren't mixed together
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix []byte
+	// partial is the end of the last write when it didn't end with a newline
+	partial []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		err := w.writeLine(w.partial[:i+1])
+		if err != nil {
+			return 0, err
+		}
+		w.partial = w.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// flush writes the remaining partial line after the process exits
+func (w *prefixWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) == 0 {
+		return
+	}
+
+	_ = w.writeLine(append(w.partial, '\n'))
+	w.partial = nil
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	_, err := w.out.Write(append(w.prefix[:len(w.prefix):len(w.prefix)], line...))
+	return err
+}
@@ -0,0 +1,270 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/calvinmclean/goblin/dns"
+	"github.com/calvinmclean/goblin/errors"
+	"github.com/calvinmclean/goblin/plugins"
+)
+
+// Supervisor runs the services from a Manifest
+type Supervisor struct {
+	manifest Manifest
+	getter   plugins.ReadyGetter
+	env      plugins.EnvConfig
+	logger   *slog.Logger
+}
+
+// New creates a Supervisor that allocates IPs with the getter. Output from executables is written to stdout
+// with the service's name as a prefix. Plugins that use goblin.Env get it from env with each service's details
+func New(manifest Manifest, getter plugins.ReadyGetter, env plugins.EnvConfig, logger *slog.Logger) Supervisor {
+	return Supervisor{
+		manifest: manifest,
+		getter:   getter,
+		env:      env,
+		logger:   logger,
+	}
+}
+
+// service is the state of a running service
+type service struct {
+	Service
+	name   string
+	run    plugins.RunFunc
+	probe  plugins.Probe
+	output *prefixWriter
+	logger *slog.Logger
+
+	// ready is closed when the service is ready for the first time and stopped is closed when it won't be
+	// started again
+	ready, stopped chan struct{}
+	readyOnce      sync.Once
+	dependencies   []*service
+	dependents     []*service
+}
+
+func (svc *service) setReady() {
+	svc.readyOnce.Do(func() {
+		close(svc.ready)
+	})
+}
+
+// Run builds and loads all services and then runs them in dependency order. Services are restarted when they
+// exit based on their restart setting. When the context is done, each service is stopped after the services that
+// depend on it. Run returns when all services have stopped
+func (s Supervisor) Run(ctx context.Context) error {
+	services, err := s.load(ctx)
+	if err != nil || ctx.Err() != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(services))
+	for _, svc := range services {
+		// each service has its own context so it keeps running until its dependents are stopped
+		svcCtx, svcCancel := context.WithCancel(context.WithoutCancel(ctx))
+		go func() {
+			<-ctx.Done()
+			for _, dependent := range svc.dependents {
+				<-dependent.stopped
+			}
+			svcCancel()
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(svc.stopped)
+
+			err := s.runService(svcCtx, svc)
+			if err != nil {
+				errs <- fmt.Errorf("error running service %q: %w", svc.name, err)
+				cancel()
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
+// load builds and loads the services in the order they start. Plugins are built one at a time since building
+// changes the working directory. It stops early if the context is done
+func (s Supervisor) load(ctx context.Context) ([]*service, error) {
+	order, err := s.manifest.startOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	width := 0
+	for _, name := range order {
+		width = max(width, len(name))
+	}
+
+	var mu sync.Mutex
+	byName := map[string]*service{}
+	services := make([]*service, 0, len(order))
+	for _, name := range order {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+
+		svc := &service{
+			Service: s.manifest.Services[name],
+			name:    name,
+			output:  &prefixWriter{mu: &mu, out: os.Stdout, prefix: fmt.Appendf(nil, "%-*s | ", width, name)},
+			logger:  s.logger.With("service", name),
+			ready:   make(chan struct{}),
+			stopped: make(chan struct{}),
+		}
+
+		svc.run, err = s.loadService(svc)
+		if err != nil {
+			errors.PrintUserFixableErrorInstruction(err)
+			return nil, fmt.Errorf("error loading service %q: %w", name, err)
+		}
+
+		if svc.Ready != nil {
+			svc.probe, err = svc.Ready.probe(svc.Dir)
+			if err != nil {
+				return nil, fmt.Errorf("error creating readiness probe for service %q: %w", name, err)
+			}
+		}
+
+		for _, dep := range svc.DependsOn {
+			svc.dependencies = append(svc.dependencies, byName[dep])
+			byName[dep].dependents = append(byName[dep].dependents, svc)
+		}
+
+		byName[name] = svc
+		services = append(services, svc)
+	}
+
+	return services, nil
+}
+
+func (s Supervisor) loadService(svc *service) (plugins.RunFunc, error) {
+	execPath := svc.Exec
+
+	switch {
+	case svc.Subprocess:
+		svc.logger.Info("building executable", "dir", svc.Plugin)
+		fname, err := plugins.BuildExecutable(svc.Plugin, plugins.BuildOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error building executable: %w", err)
+		}
+		execPath = fname
+	case svc.Plugin != "":
+		return s.loadPlugin(svc)
+	}
+
+	env := make([]string, 0, len(svc.Env))
+	for k, v := range svc.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+
+	return plugins.Exec(execPath, svc.Args, plugins.ExecOptions{
+		IPEnvVar: svc.EnvVar,
+		Env:      env,
+		Dir:      svc.Dir,
+		Stdout:   svc.output,
+		Stderr:   svc.output,
+	})
+}
+
+func (s Supervisor) loadPlugin(svc *service) (plugins.RunFunc, error) {
+	fname := svc.Plugin
+	if filepath.Ext(fname) != ".so" {
+		svc.logger.Info("building plugin", "dir", fname)
+		built, err := plugins.Build(fname)
+		if err != nil {
+			return nil, fmt.Errorf("error building plugin: %w", err)
+		}
+		fname = built
+	}
+
+	if svc.EnvVar != "" {
+		return plugins.LoadMainWithIPEnvVar(fname, svc.EnvVar)
+	}
+
+	var err error
+	env := s.env
+	env.Port = svc.Port
+	env.Subdomain, err = dns.NormalizeSubdomain(svc.Subdomain)
+	if err != nil {
+		return nil, err
+	}
+	if svc.TLSCert != "" {
+		env.TLS, err = plugins.LoadTLSConfig(svc.TLSCert, svc.TLSKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plugins.Load(fname, env)
+}
+
+// runService waits for the service's dependencies to be ready and then runs it until the context is done or
+// it exits and shouldn't be restarted. The IP is kept between restarts
+func (s Supervisor) runService(ctx context.Context, svc *service) error {
+	for _, dep := range svc.dependencies {
+		select {
+		case <-dep.ready:
+			continue
+		default:
+		}
+
+		svc.logger.Info("waiting for dependency to be ready", "dependency", dep.name)
+		select {
+		case <-dep.ready:
+		case <-dep.stopped:
+			svc.logger.Warn("not starting because a dependency stopped", "dependency", dep.name)
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	run := func(ctx context.Context, ip string) error {
+		defer svc.output.flush()
+		return svc.run(ctx, ip)
+	}
+
+	opts := plugins.RunOptions{
+		Probe:   svc.probe,
+		Restart: svc.Restart,
+		OnReady: svc.setReady,
+		Logger:  svc.logger,
+	}
+	if svc.Ready != nil {
+		opts.ProbeInterval = time.Duration(svc.Ready.Interval)
+	}
+
+	err := plugins.RunWithOptions(ctx, run, s.getter, svc.Subdomain, opts)
+	if ctx.Err() != nil {
+		svc.logger.Info("stopped service")
+		return nil
+	}
+
+	// services only stop with an error when the IP couldn't be allocated. Other errors are from the service
+	if errors.Is(err, plugins.ErrGettingIP) {
+		return err
+	}
+
+	svc.logger.Info("service exited", "error", err)
+	return nil
+}
@@ -0,0 +1,78 @@
+package watch
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// Poll checks the file or directory tree at path every interval and calls onChange when
+// anything in it has been modified, created, or removed. This avoids platform-specific
+// notification APIs at the cost of a small delay. It blocks until the context is done
+func Poll(ctx context.Context, path string, interval time.Duration, onChange func()) error {
+	last, err := Fingerprint(path)
+	if err != nil {
+		return fmt.Errorf("error reading %q: %w", path, err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := Fingerprint(path)
+		if err != nil {
+			// the file might be in the middle of being replaced, so check again next time
+			continue
+		}
+
+		if current != last {
+			last = current
+			onChange()
+		}
+	}
+}
+
+// Fingerprint summarizes the names, sizes, and modification times of a file or of every
+// file in a directory tree. Hidden directories and built plugins (*.so) are skipped
+func Fingerprint(path string) (string, error) {
+	h := sha256.New()
+
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if p != path && len(d.Name()) > 1 && d.Name()[0] == '.' {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(p) == ".so" {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(h, "%s %d %d\n", p, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}